@@ -0,0 +1,115 @@
+package timber
+
+import (
+	"time"
+)
+
+// Options for ConfigLogger.Async, the same as the options for a Timber's own buffer
+// except that a full buffer drops records by default.  Blocking would hold up the
+// dispatch goroutine and every other logger until the writer returns.
+// The records dropped by the Overflow policy are reported with a WARNING written to
+// the same logger.
+// Defaults:
+//   BufferSize         = DefaultBufferSize
+//   Overflow           = OverflowDropNewest
+//   DropLevel          = ERROR
+//   DropReportInterval = DefaultDropReportInterval
+type AsyncOptions TimberOptions
+
+// The buffer and goroutine that format and write the records of a ConfigLogger with
+// Async set.  Records are only added by the dispatch goroutine
+type writerQueue struct {
+	writer         LogWriter
+	formatter      LogFormatter
+	records        chan *LogRecord
+	overflow       OverflowPolicy
+	dropLevel      Level
+	reportInterval time.Duration
+	drops          *dropCounter
+	flushes        chan chan bool
+	done           chan bool
+}
+
+// Starts the goroutine for a logger with Async set, nil otherwise
+func newWriterQueue(cLog ConfigLogger) *writerQueue {
+	if cLog.Async == nil || cLog.LogWriter == nil {
+		return nil
+	}
+	options := TimberOptions(*cLog.Async)
+	if options.Overflow == OverflowDefault {
+		options.Overflow = OverflowDropNewest
+	}
+	options = options.withDefaults()
+	q := &writerQueue{
+		writer:         cLog.LogWriter,
+		formatter:      cLog.Formatter,
+		records:        make(chan *LogRecord, options.BufferSize),
+		overflow:       options.Overflow,
+		dropLevel:      options.DropLevel,
+		reportInterval: options.DropReportInterval,
+		drops:          new(dropCounter),
+		flushes:        make(chan chan bool),
+		done:           make(chan bool),
+	}
+	go q.writeLoop()
+	return q
+}
+
+func (q *writerQueue) writeLoop() {
+	defer close(q.done)
+	// only report drops if records can be dropped
+	var dropTick <-chan time.Time
+	if q.overflow != OverflowBlock {
+		dropTicker := time.NewTicker(q.reportInterval)
+		defer dropTicker.Stop()
+		dropTick = dropTicker.C
+	}
+	for {
+		select {
+		case rec, ok := <-q.records:
+			if !ok {
+				// stopped, everything queued has been written
+				q.reportDrops(time.Now())
+				return
+			}
+			q.writer.LogWrite(q.formatter.Format(rec))
+		case flushed := <-q.flushes:
+			// everything queued before the flush was requested is already in records
+			for len(q.records) > 0 {
+				q.writer.LogWrite(q.formatter.Format(<-q.records))
+			}
+			flushWriter(q.writer)
+			close(flushed)
+		case now := <-dropTick:
+			q.reportDrops(now)
+		}
+	}
+}
+
+func (q *writerQueue) reportDrops(now time.Time) {
+	if rec := q.drops.report(now); rec != nil {
+		q.writer.LogWrite(q.formatter.Format(rec))
+	}
+}
+
+// Queue the record following the overflow policy.  Only called on the dispatch goroutine
+func (q *writerQueue) write(rec *LogRecord) {
+	sendOverflow(q.records, rec, q.overflow, q.dropLevel, q.drops)
+}
+
+// Wait for the queued records to be written and flushed.  Only called on the dispatch goroutine
+func (q *writerQueue) flush() {
+	flushed := make(chan bool)
+	q.flushes <- flushed
+	<-flushed
+}
+
+// Wait for the queued records to be written and stop the goroutine.  The
+// writer is left open.  Only called on the dispatch goroutine
+func (q *writerQueue) stop() {
+	if q == nil {
+		return
+	}
+	close(q.records)
+	<-q.done
+}
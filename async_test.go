@@ -0,0 +1,156 @@
+package timber
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsyncLogger(t *testing.T) {
+	log := NewTimber()
+	slow := newGateWriter()
+	fast := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: slow, Level: DEBUG, Formatter: NewPatFormatter("%L %M"),
+		Async: &AsyncOptions{BufferSize: 10}})
+	log.AddLogger(ConfigLogger{LogWriter: fast, Level: DEBUG, Formatter: NewPatFormatter("%L %M")})
+	for i := 1; i <= 3; i++ {
+		log.Info("%d", i)
+	}
+	<-slow.started
+	// the blocked writer doesn't hold up the other logger
+	log.SetLevel(1, INFO)
+	checkMsgs(t, fast.msgs, []string{"INFO 1\n", "INFO 2\n", "INFO 3\n"})
+	close(slow.gate)
+	log.Close()
+	checkMsgs(t, slow.msgs, []string{"INFO 1\n", "INFO 2\n", "INFO 3\n"})
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	log := NewTimber()
+	writer := newGateWriter()
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%L %M"),
+		Async: &AsyncOptions{BufferSize: 1, Overflow: OverflowDropNewest}})
+	log.AddLogger(ConfigLogger{LogWriter: new(memWriter), Level: DEBUG, Formatter: NewPatFormatter("%M")})
+	log.Info("1")
+	<-writer.started
+	for i := 2; i <= 4; i++ {
+		log.Info("%d", i)
+	}
+	// wait for the dispatch goroutine, modifying the async logger would wait for its writer
+	log.SetLevel(1, DEBUG)
+	close(writer.gate)
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"INFO 1\n", "INFO 2\n", "WARN TIMBER! Dropped 2 records because the buffer was full\n"})
+	if log.Dropped() != 0 {
+		t.Errorf("got %d dropped from the Timber buffer, expected 0", log.Dropped())
+	}
+}
+
+func TestAsyncHungWriter(t *testing.T) {
+	log := NewTimber()
+	hung := newGateWriter()
+	fast := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: hung, Level: DEBUG, Formatter: NewPatFormatter("%M"),
+		Async: &AsyncOptions{BufferSize: 1}})
+	log.AddLogger(ConfigLogger{LogWriter: fast, Level: DEBUG, Formatter: NewPatFormatter("%M")})
+	log.Info("first")
+	<-hung.started
+	logged := make(chan bool)
+	go func() {
+		// fills the hung logger's buffer
+		for i := 0; i < 10; i++ {
+			log.Info("%d", i)
+		}
+		log.SetLevel(1, DEBUG)
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hung writer held up the other logger")
+	}
+	if len(fast.msgs) != 11 {
+		t.Errorf("got %d writes, expected 11", len(fast.msgs))
+	}
+	close(hung.gate)
+	log.Close()
+}
+
+func TestConfigAsync(t *testing.T) {
+	config := `{"filters": [{"enabled": true, "tag": "net", "type": "console", "level": "INFO",
+		"async": {"buffersize": "5", "overflow": "DropBelowLevel", "droplevel": "WARNING"}}]}`
+	loaded, err := ReadConfig(strings.NewReader(config), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	options := filterAsync(loaded.Filters[0])
+	expected := AsyncOptions{BufferSize: 5, Overflow: OverflowDropBelowLevel, DropLevel: WARNING}
+	if options == nil || *options != expected {
+		t.Errorf("got %+v, expected %+v", options, expected)
+	}
+
+	bad := &Config{Filters: []FilterConfig{{Enabled: true, Tag: "bad", Type: "console", Level: "INFO",
+		Async: &AsyncConfig{Overflow: "spill"}}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), `unknown overflow policy "spill"`) {
+		t.Errorf("got %v, expected unknown overflow policy", err)
+	}
+}
+
+type countingStringer int
+
+func (c *countingStringer) String() string {
+	*c++
+	return "formatted"
+}
+
+func TestIsEnabled(t *testing.T) {
+	log := NewTimber()
+	if log.Enabled() || log.IsEnabled(CRITICAL) {
+		t.Errorf("enabled without any loggers")
+	}
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: INFO, Formatter: NewPatFormatter("%L %M"),
+		Granulars: map[string]Level{"path/to/package": DEBUG}})
+	if !log.Enabled() || log.IsEnabled(FINE) || !log.IsEnabled(DEBUG) || !log.IsEnabled(ERROR) {
+		t.Errorf("got FINE %v DEBUG %v ERROR %v, expected DEBUG and above",
+			log.IsEnabled(FINE), log.IsEnabled(DEBUG), log.IsEnabled(ERROR))
+	}
+
+	// the message isn't formatted for disabled levels
+	count := new(countingStringer)
+	log.Finest("%v", count)
+	log.Fine("%v", count)
+	if *count != 0 {
+		t.Errorf("formatted %d disabled messages", *count)
+	}
+	log.Info("%v", count)
+
+	log.SetLevel(0, NONE)
+	if !log.IsEnabled(FINEST) {
+		t.Errorf("FINEST disabled after SetLevel")
+	}
+	log.RemoveLogger(0)
+	if log.Enabled() {
+		t.Errorf("enabled after RemoveLogger")
+	}
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"INFO formatted\n"})
+}
+
+func TestFlushAsync(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%M"), Async: &AsyncOptions{}})
+	for i := 0; i < 100; i++ {
+		log.Info("%d", i)
+	}
+	log.Flush()
+	if len(writer.msgs) != 100 {
+		t.Errorf("got %d writes after Flush, expected 100", len(writer.msgs))
+	}
+	log.Close()
+	log.Flush() // returns once closed
+}
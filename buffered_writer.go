@@ -16,7 +16,7 @@ type BufferedWriter struct {
 	buf       *bufio.Writer
 	writer    io.WriteCloser
 	mc        chan string
-	fc        chan int
+	fc        chan chan bool
 	autoFlush *time.Ticker
 
 	closeChan  chan bool
@@ -28,7 +28,7 @@ func NewBufferedWriter(writer io.WriteCloser) (*BufferedWriter, error) {
 	bw.writer = writer
 	bw.buf = bufio.NewWriter(writer)
 	bw.mc = make(chan string)
-	bw.fc = make(chan int)
+	bw.fc = make(chan chan bool)
 	bw.autoFlush = time.NewTicker(time.Second)
 	bw.closeChan = make(chan bool)
 	bw.closedChan = make(chan bool)
@@ -41,8 +41,9 @@ func (bw *BufferedWriter) writeLoop() {
 		select {
 		case msg := <-bw.mc:
 			bw.writeMessage(msg)
-		case <-bw.fc:
+		case done := <-bw.fc:
 			bw.flush()
+			close(done)
 		case <-bw.autoFlush.C:
 			bw.flush()
 		case <-bw.closeChan:
@@ -88,9 +89,15 @@ func (bw *BufferedWriter) LogWrite(msg string) {
 	}
 }
 
-// Force flush the buffer
+// Force flush the buffer and wait for it
 func (bw *BufferedWriter) Flush() error {
-	bw.fc <- 1
+	done := make(chan bool)
+	select {
+	case <-bw.closedChan:
+		// writer is closed.  everything was flushed
+	case bw.fc <- done:
+		<-done
+	}
 	return nil
 }
 
@@ -1,25 +1,909 @@
 package timber
 
 import (
-	"log"
+	"fmt"
+	"io"
+	"os"
 	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-func (t *Timber) LoadConfig(filename string) {
-	if len(filename) <= 0 {
-		return
+// Loads an XML, JSON, YAML or TOML config file depending on the file extension
+func (t *Timber) LoadConfig(filename string) error {
+	config, err := readConfig(filename)
+	if err != nil {
+		return err
+	}
+	return t.LoadConfigModel(config)
+}
+
+// Loads a config from r, such as an embedded file or stdin.  configType is
+// one of xml, json, yaml, yml or toml
+func (t *Timber) LoadConfigReader(r io.Reader, configType string) error {
+	config, err := ReadConfig(r, configType)
+	if err != nil {
+		return err
 	}
-	ext := path.Ext(filename)
-	ext = ext[1:]
+	return t.LoadConfigModel(config)
+}
 
-	switch ext {
+// Parse a config into the shared model without loading it.  configType is
+// one of xml, json, yaml, yml or toml.  Environment variables are expanded
+// and the environment overrides are applied (see Config.ExpandEnv and
+// Config.ApplyEnvOverrides)
+func ReadConfig(r io.Reader, configType string) (*Config, error) {
+	var config *Config
+	var err error
+	switch strings.ToLower(configType) {
 	case "xml":
-		t.LoadXMLConfig(filename)
-		break
+		config, err = ReadXMLConfig(r)
 	case "json":
-		t.LoadJSONConfig(filename)
-		break
+		config, err = ReadJSONConfig(r)
+	case "yaml", "yml":
+		config, err = ReadYAMLConfig(r)
+	case "toml":
+		config, err = ReadTOMLConfig(r)
+	default:
+		return nil, fmt.Errorf("TIMBER! Unknown config type %q, only XML, JSON, YAML and TOML are supported types", configType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	config.ExpandEnv()
+	if err = config.ApplyEnvOverrides(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func readConfig(filename string) (*Config, error) {
+	return readConfigFile(filename, strings.TrimPrefix(path.Ext(filename), "."))
+}
+
+func readConfigFile(filename, configType string) (*Config, error) {
+	if len(filename) <= 0 {
+		return nil, fmt.Errorf("TIMBER! Empty config filename")
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("TIMBER! Can't load %s config file: %s %v", configType, filename, err)
+	}
+	defer file.Close()
+
+	config, err := ReadConfig(file, configType)
+	if err != nil {
+		return nil, fmt.Errorf("%v (%s)", err, filename)
+	}
+	return config, nil
+}
+
+// The config file formats are all converted to this model which is validated
+// and then loaded.  Filters correspond to <filter> elements.
+type Config struct {
+	Filters []FilterConfig
+}
+
+type FilterConfig struct {
+	Enabled bool
+	Tag     string
+	Type    string
+	Level   string
+	// Name of the formatter type, empty for the pattern formatter
+	Format string
+	// Properties by name, the format pattern is the "format" property
+	Properties map[string]string
+	Granulars  []GranularConfig
+	// Records must match one of the includes, if there are any, and none of the excludes
+	Includes []MatchConfig
+	Excludes []MatchConfig
+	// nil if the filter isn't rate limited
+	RateLimit *RateLimitConfig
+	// Duration to collapse repeated messages for, empty to write them all
+	Dedup string
+	// nil if the filter writes on the dispatch goroutine
+	Async *AsyncConfig
+	// Level at and above which stacks are written, empty for the Timber's stack level
+	// or off for none
+	StackLevel string
+}
+
+// A <ratelimit>, see RateLimit.  Empty values are the defaults
+type RateLimitConfig struct {
+	Rate            string // records per second for each level
+	Burst           string
+	First           string
+	Thereafter      string
+	SampleInterval  string // a duration like 1s
+	SummaryInterval string
+}
+
+// An <async>, see AsyncOptions.  Empty values are the defaults
+type AsyncConfig struct {
+	BufferSize         string
+	Overflow           string // block, dropnewest (the default), dropoldest or dropbelowlevel
+	DropLevel          string
+	DropReportInterval string // a duration like 1m
+}
+
+type GranularConfig struct {
+	Level string
+	Path  string
+}
+
+// An <include> or <exclude>.  A record matches if every condition that is set matches
+type MatchConfig struct {
+	Message string            // regular expression matched against the message
+	Source  string            // source file pattern, see SourceFilter
+	Fields  map[string]string // field values by key
+}
+
+// Convert a parsed YAML or TOML document into the shared model.  The document
+// has the same structure as the JSON config, keys are case insensitive and
+// numbers and booleans are read as their text
+func configFromTree(tree map[string]interface{}) (*Config, error) {
+	config := JSONConfig{}
+	filters, err := treeList(tree, "filters")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range filters {
+		filter := JSONFilter{}
+		if filter.Tag, err = treeString(f, "tag"); err != nil {
+			return nil, err
+		}
+		enabled, err := treeString(f, "enabled")
+		if err != nil {
+			return nil, err
+		}
+		if enabled != "" {
+			if filter.Enabled, err = strconv.ParseBool(enabled); err != nil {
+				return nil, fmt.Errorf("filter %q enabled: %v", filter.Tag, err)
+			}
+		}
+		if filter.Type, err = treeString(f, "type"); err != nil {
+			return nil, err
+		}
+		if filter.Level, err = treeString(f, "level"); err != nil {
+			return nil, err
+		}
+		if format, ok := treeValue(f, "format"); ok {
+			m, ok := format.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("filter %q format: expected name and value", filter.Tag)
+			}
+			if filter.Format.Name, err = treeString(m, "name"); err != nil {
+				return nil, err
+			}
+			if filter.Format.Value, err = treeString(m, "value"); err != nil {
+				return nil, err
+			}
+		}
+		properties, err := treeList(f, "properties")
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range properties {
+			property := JSONProperty{}
+			if property.Name, err = treeString(p, "name"); err != nil {
+				return nil, err
+			}
+			if property.Value, err = treeString(p, "value"); err != nil {
+				return nil, err
+			}
+			filter.Properties = append(filter.Properties, property)
+		}
+		granulars, err := treeList(f, "granulars")
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range granulars {
+			granular := JSONGranular{}
+			if granular.Level, err = treeString(g, "level"); err != nil {
+				return nil, err
+			}
+			if granular.Path, err = treeString(g, "path"); err != nil {
+				return nil, err
+			}
+			filter.Granulars = append(filter.Granulars, granular)
+		}
+		if filter.Includes, err = treeMatches(f, "includes"); err != nil {
+			return nil, err
+		}
+		if filter.Excludes, err = treeMatches(f, "excludes"); err != nil {
+			return nil, err
+		}
+		if filter.Dedup, err = treeString(f, "dedup"); err != nil {
+			return nil, err
+		}
+		if filter.StackLevel, err = treeString(f, "stacklevel"); err != nil {
+			return nil, err
+		}
+		if rateLimit, ok := treeValue(f, "ratelimit"); ok {
+			filter.RateLimit = &JSONRateLimit{}
+			if err = treeStrings(rateLimit, filter.Tag, "ratelimit", map[string]*string{
+				"rate":            &filter.RateLimit.Rate,
+				"burst":           &filter.RateLimit.Burst,
+				"first":           &filter.RateLimit.First,
+				"thereafter":      &filter.RateLimit.Thereafter,
+				"sampleinterval":  &filter.RateLimit.SampleInterval,
+				"summaryinterval": &filter.RateLimit.SummaryInterval,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if async, ok := treeValue(f, "async"); ok {
+			filter.Async = &JSONAsync{}
+			if err = treeStrings(async, filter.Tag, "async", map[string]*string{
+				"buffersize":         &filter.Async.BufferSize,
+				"overflow":           &filter.Async.Overflow,
+				"droplevel":          &filter.Async.DropLevel,
+				"dropreportinterval": &filter.Async.DropReportInterval,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		config.Filters = append(config.Filters, filter)
+	}
+	return config.Config(), nil
+}
+
+// Set the values of a table like ratelimit by key
+func treeStrings(table interface{}, tag, name string, values map[string]*string) error {
+	m, ok := table.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("filter %q %s: expected a table", tag, name)
+	}
+	for key, value := range values {
+		var err error
+		if *value, err = treeString(m, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func treeMatches(tree map[string]interface{}, key string) ([]JSONMatch, error) {
+	items, err := treeList(tree, key)
+	if err != nil {
+		return nil, err
+	}
+	var matches []JSONMatch
+	for _, item := range items {
+		match := JSONMatch{}
+		if match.Message, err = treeString(item, "message"); err != nil {
+			return nil, err
+		}
+		if match.Source, err = treeString(item, "source"); err != nil {
+			return nil, err
+		}
+		fields, err := treeList(item, "fields")
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			field := JSONProperty{}
+			if field.Name, err = treeString(f, "name"); err != nil {
+				return nil, err
+			}
+			if field.Value, err = treeString(f, "value"); err != nil {
+				return nil, err
+			}
+			match.Fields = append(match.Fields, field)
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+func treeValue(tree map[string]interface{}, key string) (interface{}, bool) {
+	for k, v := range tree {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func treeString(tree map[string]interface{}, key string) (string, error) {
+	v, ok := treeValue(tree, key)
+	if !ok {
+		return "", nil
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("%s: expected a single value", key)
+}
+
+// A missing list is empty
+func treeList(tree map[string]interface{}, key string) ([]map[string]interface{}, error) {
+	v, ok := treeValue(tree, key)
+	if !ok || v == nil || v == "" {
+		return nil, nil
+	}
+	if tables, ok := v.([]map[string]interface{}); ok {
+		// TOML arrays of tables
+		return tables, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a list", key)
+	}
+	ret := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a list of tables", key)
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+// A problem with one field of a filter found by Config.Validate
+type ConfigError struct {
+	Tag     string
+	Field   string
+	Problem string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("filter %q %s: %s", e.Tag, e.Field, e.Problem)
+}
+
+// All of the problems in a Config
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "TIMBER! Invalid config: " + strings.Join(msgs, "; ")
+}
+
+func (errs *ConfigErrors) add(tag, field, format string, args ...interface{}) {
+	*errs = append(*errs, &ConfigError{tag, field, fmt.Sprintf(format, args...)})
+}
+
+// Add err from a writer or formatter type, reported under the property it names
+// or field otherwise
+func (errs *ConfigErrors) addProperty(tag, field string, err error) {
+	if propErr, ok := err.(*PropertyError); ok {
+		errs.add(tag, propErr.Property, "%s", propErr.Problem)
+		return
+	}
+	errs.add(tag, field, "%v", err)
+}
+
+// A problem with one <property> of a filter.  Writer validators and formatter factories
+// return it so Config.Validate reports the property rather than the type or format
+type PropertyError struct {
+	Property string
+	Problem  string
+}
+
+func (e *PropertyError) Error() string {
+	return fmt.Sprintf("TIMBER! Bad %s property: %s", e.Property, e.Problem)
+}
+
+// Networks accepted by the socket writer's "protocol" property
+var socketProtocols = map[string]bool{
+	"tcp": true, "tcp4": true, "tcp6": true,
+	"udp": true, "udp4": true, "udp6": true,
+	"unix": true, "unixgram": true, "unixpacket": true,
+}
+
+// Checks the enabled filters and returns ConfigErrors listing every problem
+// or nil if the config is ok.  Disabled filters are only checked for duplicate tags.
+// The properties are checked by the validator of the writer type and by building
+// the formatter, so nothing is opened
+func (c *Config) Validate() error {
+	var errs ConfigErrors
+	tags := make(map[string]bool)
+	for _, filter := range c.Filters {
+		if filter.Tag != "" {
+			if tags[filter.Tag] {
+				errs.add(filter.Tag, "tag", "duplicate tag")
+			}
+			tags[filter.Tag] = true
+		}
+		if !filter.Enabled {
+			continue
+		}
+		if _, err := parseLevel(filter.Level); err != nil {
+			errs.add(filter.Tag, "level", "%v", err)
+		}
+		for _, granular := range filter.Granulars {
+			if granular.Path == "" {
+				errs.add(filter.Tag, "granular", "missing path")
+			}
+			if _, err := parseLevel(granular.Level); err != nil {
+				errs.add(filter.Tag, "granular "+granular.Path, "%v", err)
+			}
+		}
+		validateMatches(&errs, filter.Tag, "include", filter.Includes)
+		validateMatches(&errs, filter.Tag, "exclude", filter.Excludes)
+		if filter.RateLimit != nil {
+			if _, err := filter.RateLimit.RateLimit(); err != nil {
+				errs.add(filter.Tag, "ratelimit", "%v", err)
+			}
+		}
+		if filter.Dedup != "" {
+			if window, err := time.ParseDuration(filter.Dedup); err != nil || window <= 0 {
+				errs.add(filter.Tag, "dedup", "bad duration %q", filter.Dedup)
+			}
+		}
+		if _, err := parseStackLevel(filter.StackLevel); err != nil {
+			errs.add(filter.Tag, "stacklevel", "%v", err)
+		}
+		if filter.Async != nil {
+			if _, err := filter.Async.AsyncOptions(); err != nil {
+				errs.add(filter.Tag, "async", "%v", err)
+			}
+		}
+		if writerType, ok := getWriterType(filter.Type); !ok {
+			errs.add(filter.Tag, "type", "unknown writer type %q", filter.Type)
+		} else if writerType.validate != nil {
+			for _, err := range writerType.validate(filter.Properties) {
+				errs.addProperty(filter.Tag, "type", err)
+			}
+		}
+		if filter.Format != "" && getFormatterFactory(filter.Format) == nil {
+			errs.add(filter.Tag, "format", "unknown format type %q", filter.Format)
+		} else if _, err := newFilterFormatter(filter); err != nil {
+			errs.addProperty(filter.Tag, "format", err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateMatches(errs *ConfigErrors, tag, field string, matches []MatchConfig) {
+	for _, match := range matches {
+		if match.Message == "" && match.Source == "" && len(match.Fields) == 0 {
+			errs.add(tag, field, "no message, source or field to match")
+		}
+		if _, err := regexp.Compile(match.Message); err != nil {
+			errs.add(tag, field+" message", "%v", err)
+		}
+		if _, err := path.Match(match.Source, ""); err != nil {
+			errs.add(tag, field+" source", "bad pattern %q", match.Source)
+		}
+	}
+}
+
+// Parse the values.  Numbers must not be negative and intervals must be positive
+func (c *RateLimitConfig) RateLimit() (*RateLimit, error) {
+	limit := &RateLimit{}
+	var err error
+	if c.Rate != "" {
+		if limit.Rate, err = strconv.ParseFloat(c.Rate, 64); err != nil || limit.Rate < 0 {
+			return nil, fmt.Errorf("bad rate %q", c.Rate)
+		}
+	}
+	for _, n := range []struct {
+		name  string
+		value string
+		dest  *int
+	}{
+		{"burst", c.Burst, &limit.Burst},
+		{"first", c.First, &limit.First},
+		{"thereafter", c.Thereafter, &limit.Thereafter},
+	} {
+		if n.value == "" {
+			continue
+		}
+		if *n.dest, err = strconv.Atoi(n.value); err != nil || *n.dest < 0 {
+			return nil, fmt.Errorf("bad %s %q", n.name, n.value)
+		}
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"sample interval", c.SampleInterval, &limit.SampleInterval},
+		{"summary interval", c.SummaryInterval, &limit.SummaryInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if *d.dest, err = time.ParseDuration(d.value); err != nil || *d.dest <= 0 {
+			return nil, fmt.Errorf("bad %s %q", d.name, d.value)
+		}
+	}
+	return limit, nil
+}
+
+// Validates the config and creates a ConfigLogger for each enabled filter.
+// If a writer still fails, like a file that can't be created, the writers that
+// were already created are closed so nothing is left open
+func (c *Config) ConfigLoggers() ([]ConfigLogger, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var errs ConfigErrors
+	loggers := make([]ConfigLogger, 0, len(c.Filters))
+	for _, filter := range c.Filters {
+		if !filter.Enabled {
+			continue
+		}
+		configLogger, err := newFilterLogger(filter)
+		if err != nil {
+			errs.addProperty(filter.Tag, "type", err)
+			continue
+		}
+		loggers = append(loggers, configLogger)
+	}
+	if len(errs) > 0 {
+		closeAllWriters(loggers)
+		return nil, errs
+	}
+	return loggers, nil
+}
+
+// Adds all of the loggers in the config or none of them if there are any problems
+func (t *Timber) LoadConfigModel(config *Config) error {
+	loggers, err := config.ConfigLoggers()
+	if err != nil {
+		return err
+	}
+	for i, configLogger := range loggers {
+		if t.AddLogger(configLogger) < 0 {
+			// closed in the meantime so nothing else will close the writers that are left
+			for _, left := range loggers[i:] {
+				left.LogWriter.Close()
+			}
+			return fmt.Errorf("TIMBER! Can't add a logger after Close")
+		}
+	}
+	return nil
+}
+
+// Creates a LogWriter for a filter <type>.  properties holds the filter's
+// <property> values by name
+type WriterFactory func(properties map[string]string) (LogWriter, error)
+
+// Checks the properties for a writer type without creating the writer so Config.Validate
+// can report every problem before any file or socket is opened.  Return a PropertyError
+// for a problem with one property
+type WriterValidator func(properties map[string]string) []error
+
+// Creates a LogFormatter for a <format name="...">.  properties holds the filter's
+// <property> values by name with "format" set to the pattern from the <format> element
+// or the format property.  Config.Validate also calls it to check the properties so it
+// shouldn't have side effects.  Return a PropertyError for a problem with one property
+type FormatterFactory func(properties map[string]string) (LogFormatter, error)
+
+type writerType struct {
+	factory  WriterFactory
+	validate WriterValidator
+}
+
+var (
+	registryLock       sync.RWMutex
+	writerTypes        = make(map[string]writerType)
+	formatterFactories = make(map[string]FormatterFactory)
+)
+
+// Make a writer type available to config files as <type>name</type>.
+// Registering an existing name replaces it, including the built-in types
+// and their validators
+func RegisterWriterType(name string, factory WriterFactory) {
+	RegisterValidatedWriterType(name, factory, nil)
+}
+
+// Like RegisterWriterType with a validator for the properties that Config.Validate runs
+func RegisterValidatedWriterType(name string, factory WriterFactory, validate WriterValidator) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	writerTypes[name] = writerType{factory, validate}
+}
+
+// Make a formatter type available to config files as <format name="name">.
+// Registering an existing name replaces it, including the built-in types
+func RegisterFormatterType(name string, factory FormatterFactory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	formatterFactories[name] = factory
+}
+
+func getWriterType(name string) (writerType, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	writerType, ok := writerTypes[name]
+	return writerType, ok
+}
+
+func getFormatterFactory(name string) FormatterFactory {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return formatterFactories[name]
+}
+
+// Built-in writer types:
+//   console - ConsoleWriter
+//   socket  - SocketWriter to the "protocol" and "endpoint" properties
+//   file    - FileWriter to the "filename" property
+// Built-in formatter types:
+//   pattern - PatFormatter using format, defaults to %M
+//   json    - JSONFormatter, the "timeformat" property sets the time layout and
+//             a "levelformat" property of "short" uses the 4 character level names
+//   logfmt  - LogfmtFormatter, the "timeformat" property sets the time layout and
+//             "attributes" is a comma separated list of record attributes to include
+//   syslog  - SyslogFormatter wrapping a PatFormatter using format, an "rfc" property
+//             of "5424" rather than "3164" selects RFC 5424 output with optional "msgid" and "sdid" properties.
+//             "facility" (e.g. local3), "tag", "hostname" and "severities" (see
+//             ParseSeverityMap) override the defaults
+func init() {
+	RegisterWriterType("console", newConfigConsoleWriter)
+	RegisterValidatedWriterType("socket", newConfigSocketWriter, validateConfigSocketWriter)
+	RegisterValidatedWriterType("file", newConfigFileWriter, validateConfigFileWriter)
+	RegisterFormatterType("pattern", newConfigPatFormatter)
+	RegisterFormatterType("json", newConfigJSONFormatter)
+	RegisterFormatterType("logfmt", newConfigLogfmtFormatter)
+	RegisterFormatterType("syslog", newConfigSyslogFormatter)
+}
+
+// Build a ConfigLogger for a validated filter using the registered writer and formatter types
+func newFilterLogger(filter FilterConfig) (ConfigLogger, error) {
+	formatter, err := newFilterFormatter(filter)
+	if err != nil {
+		return ConfigLogger{}, err
+	}
+	writerType, ok := getWriterType(filter.Type)
+	if !ok {
+		return ConfigLogger{}, fmt.Errorf("unknown writer type %q", filter.Type)
+	}
+	writer, err := writerType.factory(filter.Properties)
+	if err != nil {
+		return ConfigLogger{}, err
+	}
+	level, granulars := filterLevels(filter)
+	return ConfigLogger{
+		LogWriter:   writer,
+		Level:       level,
+		Formatter:   formatter,
+		Granulars:   granulars,
+		Filter:      newFilterLogFilter(filter),
+		RateLimit:   filterRateLimit(filter),
+		DedupWindow: filterDedup(filter),
+		Async:       filterAsync(filter),
+		StackLevel:  filterStackLevel(filter),
+	}, nil
+}
+
+// An empty format name is the pattern formatter
+func newFilterFormatter(filter FilterConfig) (LogFormatter, error) {
+	formatName := filter.Format
+	if formatName == "" {
+		formatName = "pattern"
+	}
+	formatterFactory := getFormatterFactory(formatName)
+	if formatterFactory == nil {
+		return nil, fmt.Errorf("unknown format type %q", formatName)
+	}
+	return formatterFactory(filter.Properties)
+}
+
+// The levels were already checked by Validate
+func filterLevels(filter FilterConfig) (Level, map[string]Level) {
+	level, _ := parseLevel(filter.Level)
+	granulars := make(map[string]Level)
+	for _, granular := range filter.Granulars {
+		granulars[granular.Path], _ = parseLevel(granular.Level)
+	}
+	return level, granulars
+}
+
+// The includes and excludes were already checked by Validate.  nil if there are none
+func newFilterLogFilter(filter FilterConfig) LogFilter {
+	if len(filter.Includes) == 0 && len(filter.Excludes) == 0 {
+		return nil
+	}
+	return IncludeExcludeFilter(matchFilters(filter.Includes), matchFilters(filter.Excludes))
+}
+
+// Parse the values.  The buffer size must not be negative and the interval must be positive
+func (c *AsyncConfig) AsyncOptions() (*AsyncOptions, error) {
+	options := &AsyncOptions{}
+	var err error
+	if c.BufferSize != "" {
+		if options.BufferSize, err = strconv.Atoi(c.BufferSize); err != nil || options.BufferSize < 0 {
+			return nil, fmt.Errorf("bad buffer size %q", c.BufferSize)
+		}
+	}
+	if c.Overflow != "" {
+		if options.Overflow, err = parseOverflowPolicy(c.Overflow); err != nil {
+			return nil, err
+		}
+	}
+	if options.DropLevel, err = parseLevel(c.DropLevel); err != nil {
+		return nil, fmt.Errorf("drop level: %v", err)
+	}
+	if c.DropReportInterval != "" {
+		options.DropReportInterval, err = time.ParseDuration(c.DropReportInterval)
+		if err != nil || options.DropReportInterval <= 0 {
+			return nil, fmt.Errorf("bad drop report interval %q", c.DropReportInterval)
+		}
+	}
+	return options, nil
+}
+
+// The rate limit was already checked by Validate
+func filterRateLimit(filter FilterConfig) *RateLimit {
+	if filter.RateLimit == nil {
+		return nil
+	}
+	limit, _ := filter.RateLimit.RateLimit()
+	return limit
+}
+
+// The window was already checked by Validate
+func filterDedup(filter FilterConfig) time.Duration {
+	window, _ := time.ParseDuration(filter.Dedup)
+	return window
+}
+
+// A level name or off for StackOff
+func parseStackLevel(lvlString string) (Level, error) {
+	if strings.EqualFold(strings.TrimSpace(lvlString), "off") {
+		return StackOff, nil
+	}
+	return parseLevel(lvlString)
+}
+
+// The stack level was already checked by Validate
+func filterStackLevel(filter FilterConfig) Level {
+	level, _ := parseStackLevel(filter.StackLevel)
+	return level
+}
+
+// The async options were already checked by Validate
+func filterAsync(filter FilterConfig) *AsyncOptions {
+	if filter.Async == nil {
+		return nil
+	}
+	options, _ := filter.Async.AsyncOptions()
+	return options
+}
+
+func matchFilters(matches []MatchConfig) []LogFilter {
+	filters := make([]LogFilter, 0, len(matches))
+	for _, match := range matches {
+		var conditions []LogFilter
+		if match.Message != "" {
+			conditions = append(conditions, MessageFilter(regexp.MustCompile(match.Message)))
+		}
+		if match.Source != "" {
+			conditions = append(conditions, SourceFilter(match.Source))
+		}
+		for key, value := range match.Fields {
+			conditions = append(conditions, FieldFilter(key, value))
+		}
+		filters = append(filters, AllFilters(conditions...))
+	}
+	return filters
+}
+
+func newConfigConsoleWriter(properties map[string]string) (LogWriter, error) {
+	return new(ConsoleWriter), nil
+}
+
+func validateConfigSocketWriter(properties map[string]string) []error {
+	var errs []error
+	if protocol := properties["protocol"]; !socketProtocols[protocol] {
+		errs = append(errs, &PropertyError{"protocol", fmt.Sprintf("bad protocol %q for socket writer", protocol)})
+	}
+	if properties["endpoint"] == "" {
+		errs = append(errs, &PropertyError{"endpoint", "missing endpoint for socket writer"})
+	}
+	return errs
+}
+
+func newConfigSocketWriter(properties map[string]string) (LogWriter, error) {
+	if errs := validateConfigSocketWriter(properties); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return NewSocketWriter(properties["protocol"], properties["endpoint"])
+}
+
+func validateConfigFileWriter(properties map[string]string) []error {
+	if properties["filename"] == "" {
+		return []error{&PropertyError{"filename", "missing filename for file writer"}}
+	}
+	return nil
+}
+
+func newConfigFileWriter(properties map[string]string) (LogWriter, error) {
+	if errs := validateConfigFileWriter(properties); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return NewFileWriter(properties["filename"])
+}
+
+func newConfigPatFormatter(properties map[string]string) (LogFormatter, error) {
+	format := properties["format"]
+	// If empty format set the default as just the message
+	if format == "" {
+		format = "%M"
+	}
+	return NewPatFormatter(format), nil
+}
+
+func newConfigJSONFormatter(properties map[string]string) (LogFormatter, error) {
+	jf := NewJSONFormatter()
+	if layout := properties["timeformat"]; layout != "" {
+		jf.TimeLayout = layout
+	}
+	jf.ShortLevel = properties["levelformat"] == "short"
+	return jf, nil
+}
+
+func newConfigLogfmtFormatter(properties map[string]string) (LogFormatter, error) {
+	lf := NewLogfmtFormatter()
+	if layout := properties["timeformat"]; layout != "" {
+		lf.TimeLayout = layout
+	}
+	if attributes := properties["attributes"]; attributes != "" {
+		lf.Attributes = parseLogfmtAttributes(attributes)
+		for _, attr := range lf.Attributes {
+			if !logfmtAttributeNames[attr] {
+				return nil, &PropertyError{"attributes", fmt.Sprintf("unknown logfmt attribute %q", attr)}
+			}
+		}
+	}
+	return lf, nil
+}
+
+func newConfigSyslogFormatter(properties map[string]string) (LogFormatter, error) {
+	format := properties["format"]
+	if format == "" {
+		format = "%M"
+	}
+	sf := NewSyslogFormatter(format)
+	switch rfc := properties["rfc"]; rfc {
+	case "", "3164":
+	case "5424":
+		sf.RFC5424 = true
+		sf.MsgID = properties["msgid"]
+		if sdid := properties["sdid"]; sdid != "" {
+			sf.StructuredDataID = sdid
+		}
 	default:
-		log.Printf("TIMBER! Unknown config file type %v, only XML and JSON are supported types\n", ext)
+		return nil, &PropertyError{"rfc", fmt.Sprintf("unknown syslog rfc %q, expected 3164 or 5424", rfc)}
+	}
+	if facility := properties["facility"]; facility != "" {
+		var err error
+		if sf.Facility, err = ParseSyslogFacility(facility); err != nil {
+			return nil, &PropertyError{"facility", fmt.Sprintf("unknown syslog facility %q", facility)}
+		}
+	}
+	if tag := properties["tag"]; tag != "" {
+		sf.Tag = tag
+	}
+	if hostname := properties["hostname"]; hostname != "" {
+		sf.Hostname = hostname
+	}
+	if severities := properties["severities"]; severities != "" {
+		severityMap, err := ParseSeverityMap(severities)
+		if err != nil {
+			return nil, &PropertyError{"severities", strings.TrimPrefix(err.Error(), "TIMBER! ")}
+		}
+		sf.SeverityMap = severityMap
 	}
+	return sf, nil
 }
@@ -0,0 +1,137 @@
+package timber
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ${VAR} or ${VAR:-default}
+var envVarRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Prefix of the environment variables that override config values
+const EnvPrefix = "TIMBER_"
+
+// Replace ${VAR} with the value of the environment variable VAR and ${VAR:-default}
+// with the default when VAR is unset or empty.  Unset variables without a default
+// are replaced with an empty string
+func expandEnv(value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return envVarRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		parts := envVarRegexp.FindStringSubmatch(match)
+		if env := os.Getenv(parts[1]); env != "" || parts[2] == "" {
+			return env
+		}
+		return parts[3]
+	})
+}
+
+// Expand environment variables in every value of the config
+func (c *Config) ExpandEnv() {
+	for i := range c.Filters {
+		filter := &c.Filters[i]
+		filter.Tag = expandEnv(filter.Tag)
+		filter.Type = expandEnv(filter.Type)
+		filter.Level = expandEnv(filter.Level)
+		filter.Format = expandEnv(filter.Format)
+		filter.Dedup = expandEnv(filter.Dedup)
+		filter.StackLevel = expandEnv(filter.StackLevel)
+		for name, value := range filter.Properties {
+			filter.Properties[name] = expandEnv(value)
+		}
+		for j := range filter.Granulars {
+			filter.Granulars[j].Level = expandEnv(filter.Granulars[j].Level)
+			filter.Granulars[j].Path = expandEnv(filter.Granulars[j].Path)
+		}
+		if rl := filter.RateLimit; rl != nil {
+			for _, value := range []*string{&rl.Rate, &rl.Burst, &rl.First, &rl.Thereafter,
+				&rl.SampleInterval, &rl.SummaryInterval} {
+				*value = expandEnv(*value)
+			}
+		}
+		if async := filter.Async; async != nil {
+			for _, value := range []*string{&async.BufferSize, &async.Overflow, &async.DropLevel,
+				&async.DropReportInterval} {
+				*value = expandEnv(*value)
+			}
+		}
+		for _, matches := range [][]MatchConfig{filter.Includes, filter.Excludes} {
+			for j := range matches {
+				matches[j].Message = expandEnv(matches[j].Message)
+				matches[j].Source = expandEnv(matches[j].Source)
+				for key, value := range matches[j].Fields {
+					matches[j].Fields[key] = expandEnv(value)
+				}
+			}
+		}
+	}
+}
+
+// Apply overrides from environment variables:
+//   TIMBER_LEVEL                     - level of every filter
+//   TIMBER_FILTER_<TAG>_LEVEL        - level of the filter with that tag
+//   TIMBER_FILTER_<TAG>_ENABLED      - enable or disable the filter with that tag
+//   TIMBER_FILTER_<TAG>_<PROPERTY>   - property of the filter with that tag, e.g.
+//                                      TIMBER_FILTER_FILE_FILENAME for the filename
+// <TAG> is the tag in upper case with anything other than letters and numbers
+// replaced with '_'.  Property names are matched in lower case.
+func (c *Config) ApplyEnvOverrides() error {
+	if level, ok := os.LookupEnv(EnvPrefix + "LEVEL"); ok {
+		for i := range c.Filters {
+			c.Filters[i].Level = level
+		}
+	}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, EnvPrefix+"FILTER_") {
+			continue
+		}
+		// the longest tag wins so FILE_X_LEVEL is the level of tag "file-x" not a property of "file"
+		var filter *FilterConfig
+		var prefix string
+		for i := range c.Filters {
+			p := EnvPrefix + "FILTER_" + envName(c.Filters[i].Tag) + "_"
+			if c.Filters[i].Tag != "" && strings.HasPrefix(env, p) && len(p) > len(prefix) {
+				filter, prefix = &c.Filters[i], p
+			}
+		}
+		if filter == nil {
+			continue
+		}
+		parts := strings.SplitN(env[len(prefix):], "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		switch name, value := parts[0], parts[1]; name {
+		case "LEVEL":
+			filter.Level = value
+		case "ENABLED":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("TIMBER! Bad %sENABLED: %v", prefix, err)
+			}
+			filter.Enabled = enabled
+		default:
+			if filter.Properties == nil {
+				filter.Properties = make(map[string]string)
+			}
+			filter.Properties[strings.ToLower(name)] = value
+		}
+	}
+	return nil
+}
+
+func envName(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, tag)
+}
@@ -0,0 +1,42 @@
+package timber
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigEnv(t *testing.T) {
+	t.Setenv("TIMBER_TEST_DIR", "/var/log")
+	t.Setenv("TIMBER_TEST_EMPTY", "")
+	t.Setenv("TIMBER_LEVEL", "WARNING")
+	t.Setenv("TIMBER_FILTER_AUDIT_LOG_LEVEL", "ERROR")
+	t.Setenv("TIMBER_FILTER_STDERR_ENABLED", "false")
+	t.Setenv("TIMBER_FILTER_AUDIT_LOG_FORMAT", "%L %M")
+
+	config, err := ReadConfig(strings.NewReader(`{"filters": [
+		{"enabled": true, "tag": "audit", "type": "file", "level": "INFO",
+		 "properties": [{"name": "filename", "value": "${TIMBER_TEST_DIR}/${TIMBER_TEST_NAME:-audit}.log"}]},
+		{"enabled": true, "tag": "audit-log", "type": "${TIMBER_TEST_EMPTY:-console}", "level": "INFO"},
+		{"enabled": true, "tag": "stderr", "type": "console", "level": "${TIMBER_TEST_UNSET}"}
+	]}`), "json")
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	audit, auditLog, stderr := config.Filters[0], config.Filters[1], config.Filters[2]
+	verify(t, "filename", audit.Properties["filename"], "/var/log/audit.log")
+	verify(t, "global level", audit.Level, "WARNING")
+	verify(t, "default", auditLog.Type, "console")
+	verify(t, "filter level", auditLog.Level, "ERROR")
+	verify(t, "filter property", auditLog.Properties["format"], "%L %M")
+	if _, ok := audit.Properties["log_format"]; ok {
+		t.Errorf("override for audit-log applied to audit")
+	}
+	if stderr.Enabled {
+		t.Errorf("expected stderr to be disabled")
+	}
+
+	t.Setenv("TIMBER_FILTER_STDERR_ENABLED", "maybe")
+	if _, err := ReadConfig(strings.NewReader(`{"filters": [{"tag": "stderr"}]}`), "json"); err == nil {
+		t.Errorf("expected error for bad ENABLED override")
+	}
+}
@@ -3,8 +3,7 @@ package timber
 import (
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"reflect"
 )
 
@@ -20,6 +19,37 @@ type JSONProperty struct {
 	Value string `xml:"value"`
 }
 
+// Limits the records written by a filter, every key that is set must match:
+//   "includes": [{"message": "payment|charge", "source": "payments/*.go",
+//                 "fields": [{"name": "service", "value": "billing"}]}]
+type JSONMatch struct {
+	Message string
+	Source  string
+	Fields  []JSONProperty
+}
+
+// Rate limits and samples the records written by a filter, every key is optional:
+//   "ratelimit": {"rate": "10", "burst": "50", "first": "5", "thereafter": "100",
+//                 "sampleinterval": "1s", "summaryinterval": "1m"}
+type JSONRateLimit struct {
+	Rate            string
+	Burst           string
+	First           string
+	Thereafter      string
+	SampleInterval  string
+	SummaryInterval string
+}
+
+// Writes the records of a filter on its own goroutine, every key is optional:
+//   "async": {"buffersize": "1000", "overflow": "dropbelowlevel", "droplevel": "WARNING",
+//             "dropreportinterval": "1m"}
+type JSONAsync struct {
+	BufferSize         string
+	Overflow           string
+	DropLevel          string
+	DropReportInterval string
+}
+
 type JSONFilter struct {
 	Enabled    bool
 	Tag        string
@@ -28,6 +58,12 @@ type JSONFilter struct {
 	Format     JSONProperty
 	Properties []JSONProperty
 	Granulars  []JSONGranular
+	Includes   []JSONMatch
+	Excludes   []JSONMatch
+	RateLimit  *JSONRateLimit
+	Dedup      string
+	Async      *JSONAsync
+	StackLevel string
 }
 
 type JSONConfig struct {
@@ -35,111 +71,68 @@ type JSONConfig struct {
 }
 
 // Loads the configuration from an JSON file (as you were probably expecting)
-func (t *Timber) LoadJSONConfig(filename string) (error) {
-	if len(filename) <= 0 {
-		return fmt.Errorf("Empty filename")
-	}
-
-	file, err := os.Open(filename)
+func (t *Timber) LoadJSONConfig(filename string) error {
+	config, err := readConfigFile(filename, "json")
 	if err != nil {
-		return fmt.Errorf("TIMBER! Can't load json config file: %s %v", filename, err)
+		return err
 	}
-	defer file.Close()
+	return t.LoadConfigModel(config)
+}
 
+// Parse an JSON config into the shared model without loading it
+func ReadJSONConfig(r io.Reader) (*Config, error) {
 	config := JSONConfig{}
-	err = json.NewDecoder(file).Decode(&config)
-	if err != nil {
-		return fmt.Errorf("TIMBER! Can't parse json config file: %s %v", filename, err)
-	}
-
-	for _, filter := range config.Filters {
-		if !filter.Enabled {
-			continue
-		}
-		level := getLevel(filter.Level)
-		formatter := getJSONFormatter(filter)
-		if err != nil {
-			return err
-		}
-		granulars := make(map[string]Level)
-		for _, granular := range filter.Granulars {
-			granulars[granular.Path] = getLevel(granular.Level)
-		}
-		configLogger := ConfigLogger{Level: level, Formatter: formatter, Granulars: granulars}
-
-		switch filter.Type {
-		case "console":
-			configLogger.LogWriter = new(ConsoleWriter)
-		case "socket":
-			configLogger.LogWriter, err = getJSONSocketWriter(filter)
-			if err != nil {
-				return err
-			}
-		case "file":
-			configLogger.LogWriter, err = getJSONFileWriter(filter)
-			if err != nil {
-				return err
-			}
-		default:
-			log.Printf("TIMBER! Warning unrecognized filter in config file: %v\n", filter.Tag)
-			continue
-		}
-
-		t.AddLogger(configLogger)
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return nil, fmt.Errorf("TIMBER! Can't parse json config: %v", err)
 	}
-	return nil
+	return config.Config(), nil
 }
 
-func getJSONFormatter(filter JSONFilter) LogFormatter {
-	format := ""
-	property := JSONProperty{}
-
-	// If format field is set then use it's value, otherwise
-	// attempt to get the format field from the filters properties
-	if !reflect.DeepEqual(filter.Format, property) {
-		format = filter.Format.Value
-	} else {
+// Convert to the shared config model.  If the format element is set then its
+// value is the "format" property, otherwise the format property is used
+func (c JSONConfig) Config() *Config {
+	config := &Config{Filters: make([]FilterConfig, 0, len(c.Filters))}
+	for _, filter := range c.Filters {
+		properties := make(map[string]string)
 		for _, prop := range filter.Properties {
-			if prop.Name == "format" {
-				format = prop.Value
-			}
+			properties[prop.Name] = prop.Value
 		}
-	}
-
-	// If empty format set the default as just the message
-	if format == "" {
-		format = "%M"
-	}
-	return NewPatFormatter(format)
-}
-
-func getJSONSocketWriter(filter JSONFilter) (LogWriter, error) {
-	var protocol, endpoint string
-
-	for _, property := range filter.Properties {
-		if property.Name == "protocol" {
-			protocol = property.Value
-		} else if property.Name == "endpoint" {
-			endpoint = property.Value
+		format := ""
+		if !reflect.DeepEqual(filter.Format, JSONProperty{}) {
+			properties["format"] = filter.Format.Value
+			format = filter.Format.Name
 		}
+		granulars := make([]GranularConfig, 0, len(filter.Granulars))
+		for _, granular := range filter.Granulars {
+			granulars = append(granulars, GranularConfig{Level: granular.Level, Path: granular.Path})
+		}
+		config.Filters = append(config.Filters, FilterConfig{
+			Enabled:    filter.Enabled,
+			Tag:        filter.Tag,
+			Type:       filter.Type,
+			Level:      filter.Level,
+			Format:     format,
+			Properties: properties,
+			Granulars:  granulars,
+			Includes:   jsonMatches(filter.Includes),
+			Excludes:   jsonMatches(filter.Excludes),
+			RateLimit:  (*RateLimitConfig)(filter.RateLimit),
+			Dedup:      filter.Dedup,
+			Async:      (*AsyncConfig)(filter.Async),
+			StackLevel: filter.StackLevel,
+		})
 	}
-
-	if protocol == "" || endpoint == "" {
-		return nil, fmt.Errorf("TIMBER! Missing protocol or endpoint for socket log writer")
-	}
-	return NewSocketWriter(protocol, endpoint)
+	return config
 }
 
-func getJSONFileWriter(filter JSONFilter) (LogWriter, error) {
-	filename := ""
-
-	for _, property := range filter.Properties {
-		if property.Name == "filename" {
-			filename = property.Value
+func jsonMatches(matches []JSONMatch) []MatchConfig {
+	ret := make([]MatchConfig, 0, len(matches))
+	for _, match := range matches {
+		fields := make(map[string]string)
+		for _, field := range match.Fields {
+			fields[field.Name] = field.Value
 		}
+		ret = append(ret, MatchConfig{Message: match.Message, Source: match.Source, Fields: fields})
 	}
-	if filename == "" {
-		return nil, fmt.Errorf("TIMBER! Missing filename for file log writer")
-	}
-	return NewFileWriter(filename)
+	return ret
 }
@@ -0,0 +1,213 @@
+package timber
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type prefixFormatter struct {
+	prefix string
+}
+
+func (f *prefixFormatter) Format(rec *LogRecord) string {
+	return f.prefix + rec.Message + "\n"
+}
+
+func TestRegisteredTypes(t *testing.T) {
+	writer := new(memWriter)
+	RegisterWriterType("memory", func(properties map[string]string) (LogWriter, error) {
+		if properties["name"] != "mem" {
+			t.Errorf("missing writer property: %v", properties)
+		}
+		return writer, nil
+	})
+	RegisterFormatterType("prefixed", func(properties map[string]string) (LogFormatter, error) {
+		return &prefixFormatter{properties["format"]}, nil
+	})
+
+	config := `<logging>
+  <filter enabled="true">
+    <tag>mem</tag>
+    <type>memory</type>
+    <level>INFO</level>
+    <property name="name">mem</property>
+    <format name="prefixed">xml:</format>
+  </filter>
+</logging>`
+	filename := filepath.Join(t.TempDir(), "timber.xml")
+	if err := os.WriteFile(filename, []byte(config), 0666); err != nil {
+		t.Fatal(err)
+	}
+	log := NewTimber()
+	if err := log.LoadXMLConfig(filename); err != nil {
+		t.Fatalf("LoadXMLConfig: %v", err)
+	}
+	log.Info("registered")
+	log.Debug("dropped")
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"xml:registered\n"})
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := &Config{Filters: []FilterConfig{
+		{Enabled: true, Tag: "a", Type: "console", Level: "LOUD"},
+		{Enabled: true, Tag: "a", Type: "file", Level: "INFO"},
+		{Enabled: true, Tag: "b", Type: "socket", Level: "debug",
+			Properties: map[string]string{"protocol": "pigeon"},
+			Granulars:  []GranularConfig{{Level: "SOFT", Path: "pkg"}}},
+		{Enabled: true, Tag: "c", Type: "carrier-pigeon", Format: "morse"},
+		{Enabled: false, Tag: "d", Type: "carrier-pigeon", Level: "LOUD"},
+		{Enabled: true, Tag: "e", Type: "console", Format: "syslog",
+			Properties: map[string]string{"facility": "local9", "severities": "DEBUG=loud"}},
+		{Enabled: true, Tag: "h", Type: "console", Format: "syslog", Properties: map[string]string{"rfc": "rfc5424"}},
+		{Enabled: true, Tag: "i", Type: "console", Format: "logfmt", Properties: map[string]string{"attributes": "ts,lvl"}},
+	}}
+	err := config.Validate()
+	errs, ok := err.(ConfigErrors)
+	if !ok {
+		t.Fatalf("expected ConfigErrors, got %v", err)
+	}
+	expected := []ConfigError{
+		{"a", "level", `unknown level "LOUD"`},
+		{"a", "tag", "duplicate tag"},
+		{"a", "filename", "missing filename for file writer"},
+		{"b", "granular pkg", `unknown level "SOFT"`},
+		{"b", "protocol", `bad protocol "pigeon" for socket writer`},
+		{"b", "endpoint", "missing endpoint for socket writer"},
+		{"c", "type", `unknown writer type "carrier-pigeon"`},
+		{"c", "format", `unknown format type "morse"`},
+		{"e", "facility", `unknown syslog facility "local9"`},
+		{"h", "rfc", `unknown syslog rfc "rfc5424", expected 3164 or 5424`},
+		{"i", "attributes", `unknown logfmt attribute "lvl"`},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("got %v, expected %d problems", errs, len(expected))
+	}
+	for i, e := range expected {
+		if *errs[i] != e {
+			t.Errorf("problem %d: got %+v, expected %+v", i, *errs[i], e)
+		}
+	}
+
+	// a formatter error that isn't about one property is reported under format
+	RegisterFormatterType("picky", func(properties map[string]string) (LogFormatter, error) {
+		return nil, fmt.Errorf("not today")
+	})
+	config = &Config{Filters: []FilterConfig{{Enabled: true, Tag: "f", Type: "console", Format: "picky"}}}
+	if err := config.Validate(); err == nil || err.Error() != `TIMBER! Invalid config: filter "f" format: not today` {
+		t.Errorf("got %v, expected the format error", err)
+	}
+
+	// replacing a built-in type replaces its checks too
+	RegisterWriterType("file", func(properties map[string]string) (LogWriter, error) {
+		return new(memWriter), nil
+	})
+	defer RegisterValidatedWriterType("file", newConfigFileWriter, validateConfigFileWriter)
+	config = &Config{Filters: []FilterConfig{{Enabled: true, Tag: "g", Type: "file"}}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("got %v for a replaced file type", err)
+	}
+}
+
+func TestConfigAtomic(t *testing.T) {
+	writer := new(closeCountWriter)
+	RegisterWriterType("counted", func(properties map[string]string) (LogWriter, error) {
+		return writer, nil
+	})
+	RegisterWriterType("unreachable", func(properties map[string]string) (LogWriter, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+	config := &Config{Filters: []FilterConfig{
+		{Enabled: true, Tag: "ok", Type: "counted", Level: "INFO"},
+		{Enabled: true, Tag: "bad", Type: "unreachable", Level: "INFO"},
+	}}
+	log := NewTimber()
+	if err := log.LoadConfigModel(config); err == nil || err.Error() != `TIMBER! Invalid config: filter "bad" type: connection refused` {
+		t.Errorf("got %v, expected connection refused", err)
+	}
+	log.Info("not written")
+	log.Close()
+	if len(writer.msgs) != 0 || writer.closed != 1 {
+		t.Errorf("got %q and %d closes, expected nothing written and 1 close", writer.msgs, writer.closed)
+	}
+
+	// the writers aren't leaked once the Timber is closed
+	writer.closed = 0
+	config.Filters = config.Filters[:1]
+	if err := log.LoadConfigModel(config); err == nil || writer.closed != 1 {
+		t.Errorf("got %v and %d closes after Close, expected an error and 1 close", err, writer.closed)
+	}
+
+	if err := NewTimber().LoadConfig("timber"); err == nil {
+		t.Errorf("expected error for filename without extension")
+	}
+}
+
+func TestConfigFormats(t *testing.T) {
+	readFile := func(filename string) *Config {
+		config, err := readConfig(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", filename, err)
+		}
+		return config
+	}
+	expected := readFile("timber.json")
+	for _, filename := range []string{"timber.yaml", "timber.toml"} {
+		if config := readFile(filename); !reflect.DeepEqual(config, expected) {
+			t.Errorf("%s: got %+v, expected %+v", filename, config, expected)
+		}
+	}
+
+	yaml := "filters:\n- enabled: true\n  tag: 'it''s' # comment\n  type: console\n  level: \"INFO #1\"\n"
+	config, err := ReadConfig(strings.NewReader(yaml), "yml")
+	if err != nil {
+		t.Fatalf("yaml reader: %v", err)
+	}
+	filter := config.Filters[0]
+	if !filter.Enabled || filter.Tag != "it's" || filter.Type != "console" || filter.Level != "INFO #1" {
+		t.Errorf("yaml reader: got %+v", filter)
+	}
+
+	// flow style, block scalars and multi-line strings
+	yaml = "filters:\n  - {enabled: true, tag: flow, type: console, dedup: 30s}\n" +
+		"  - tag: block\n    type: console\n    level: |-\n      INFO\n    format: {name: pattern, value: '%L %M'}\n"
+	toml := "[[filters]]\nenabled = true\ntag = \"\"\"\nmulti\"\"\"\ntype = \"console\"\nlevel = \"INFO\"\n" +
+		"[filters.async]\nbuffersize = 5\n"
+	for configType, text := range map[string]string{"yaml": yaml, "toml": toml} {
+		config, err := ReadConfig(strings.NewReader(text), configType)
+		if err != nil {
+			t.Errorf("%s: %v", configType, err)
+		} else if err = config.Validate(); err != nil {
+			t.Errorf("%s: %v", configType, err)
+		}
+	}
+	if config, err = ReadConfig(strings.NewReader(yaml), "yaml"); err != nil || len(config.Filters) != 2 {
+		t.Fatalf("yaml: got %+v %v", config, err)
+	}
+	if filter := config.Filters[0]; filter.Tag != "flow" || !filter.Enabled || filter.Dedup != "30s" {
+		t.Errorf("yaml flow style: got %+v", filter)
+	}
+	if filter := config.Filters[1]; filter.Level != "INFO" || filter.Properties["format"] != "%L %M" {
+		t.Errorf("yaml block scalar: got %+v", filter)
+	}
+	if config, err = ReadConfig(strings.NewReader(toml), "toml"); err != nil {
+		t.Fatalf("toml: %v", err)
+	}
+	if filter := config.Filters[0]; filter.Tag != "multi" || filter.Async == nil || filter.Async.BufferSize != "5" {
+		t.Errorf("toml multi-line string: got %+v", filter)
+	}
+
+	for configType, bad := range map[string]string{
+		"yaml": "filters:\n  - enabled: true\n   tag: x\n",
+		"toml": "[[filters]]\nenabled = \"true\nf",
+		"ini":  "",
+	} {
+		if _, err := ReadConfig(strings.NewReader(bad), configType); err == nil {
+			t.Errorf("%s: expected error", configType)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package timber
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Parse a TOML config into the shared model without loading it.
+// The structure is the same as the JSON config:
+//   [[filters]]
+//   enabled = true
+//   tag = "file"
+//   type = "file"
+//   level = "FINEST"
+//   format = { name = "pattern", value = "[%D %T] [%L] %M" }
+//
+//     [[filters.properties]]
+//     name = "filename"
+//     value = "log/server.log"
+//
+//     [[filters.granulars]]
+//     level = "INFO"
+//     path = "path/to/package"
+func ReadTOMLConfig(r io.Reader) (*Config, error) {
+	tree := make(map[string]interface{})
+	if _, err := toml.NewDecoder(r).Decode(&tree); err != nil {
+		return nil, fmt.Errorf("TIMBER! Can't parse toml config: %v", err)
+	}
+	config, err := configFromTree(tree)
+	if err != nil {
+		return nil, fmt.Errorf("TIMBER! Can't parse toml config: %v", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,307 @@
+package timber
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// How often WatchConfig checks the config file for changes
+var DefaultConfigPollInterval = 2 * time.Second
+
+// The properties used by the built-in writer types.  Changes to other properties
+// of these types don't reopen the writer.  For any other writer type a change to
+// any property except the format reopens it
+var writerProperties = map[string][]string{
+	"console": {},
+	"file":    {"filename"},
+	"socket":  {"protocol", "endpoint"},
+}
+
+// Keeps the loggers of a Timber in sync with a config file.
+// Created with Timber.WatchConfig
+type ConfigWatcher struct {
+	t        *Timber
+	filename string
+	lock     sync.Mutex
+	loaded   map[string]loadedFilter // running filters by tag
+	untagged []loadedFilter          // running filters without a tag in config order
+	modTime  time.Time
+	size     int64
+	missing  bool // the file couldn't be found at the last reload
+	stop     chan bool
+	stopOnce sync.Once
+}
+
+type loadedFilter struct {
+	index  int
+	filter FilterConfig
+}
+
+// Loads the config file and reloads it whenever the file changes or the process
+// receives SIGHUP.  On reload, filters are matched to the running loggers by <tag>, and
+// filters without a tag by their position among the untagged filters and their <type>:
+// level, granular and format changes are made in place and the writer is only reopened
+// if its destination changed.  Filters that were added, removed, enabled or disabled are
+// added or removed.  If the new config is invalid the old config stays active and the
+// problem is logged with the standard log package, once until it changes.
+//
+// Loggers added by other means are not affected.  An error is returned if the initial
+// load fails in which case nothing is watched.  Watching stops when the Timber is closed.
+func (t *Timber) WatchConfig(filename string) (*ConfigWatcher, error) {
+	cw := &ConfigWatcher{
+		t:        t,
+		filename: filename,
+		loaded:   make(map[string]loadedFilter),
+		stop:     make(chan bool),
+	}
+	if err := cw.Reload(); err != nil {
+		return nil, err
+	}
+	go cw.watch(DefaultConfigPollInterval)
+	return cw, nil
+}
+
+// Stop watching.  The loggers from the config file keep running
+func (cw *ConfigWatcher) Stop() {
+	cw.stopOnce.Do(func() {
+		close(cw.stop)
+	})
+}
+
+func (cw *ConfigWatcher) watch(interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// the last problem reported
+	lastErr := ""
+	for {
+		var err error
+		select {
+		case <-cw.stop:
+			return
+		case <-cw.t.blackHole:
+			// closed so there is nothing left to configure
+			return
+		case <-hup:
+			err = cw.Reload()
+		case <-ticker.C:
+			if cw.changed() {
+				err = cw.Reload()
+			}
+		}
+		if err == nil {
+			lastErr = ""
+		} else if err.Error() != lastErr {
+			lastErr = err.Error()
+			log.Printf("TIMBER! Config reload of %s failed, keeping the old config: %v\n", cw.filename, err)
+		}
+	}
+}
+
+// Whether the file changed, or went missing, since the last reload
+func (cw *ConfigWatcher) changed() bool {
+	info, err := os.Stat(cw.filename)
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+	if err != nil {
+		return !cw.missing
+	}
+	return cw.missing || !info.ModTime().Equal(cw.modTime) || info.Size() != cw.size
+}
+
+// Re-read the config file and apply the differences to the running loggers now.
+// Nothing is changed if the config can't be read or is invalid
+func (cw *ConfigWatcher) Reload() error {
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+
+	if cw.t.isClosed() {
+		return fmt.Errorf("TIMBER! Can't reload %s after Close", cw.filename)
+	}
+	// record the file version first so a bad file is only reloaded once
+	info, err := os.Stat(cw.filename)
+	if cw.missing = err != nil; !cw.missing {
+		cw.modTime, cw.size = info.ModTime(), info.Size()
+	}
+	config, err := readConfig(cw.filename)
+	if err != nil {
+		return err
+	}
+	if err = config.Validate(); err != nil {
+		return err
+	}
+	return cw.apply(config)
+}
+
+// An enabled filter of the new config.  If the filter is running with the same
+// destination the change is made in place and logger only has the settings that
+// don't need a new writer:
+//   - Formatter
+//   - Filter, RateLimit and DedupWindow
+//   - Async and StackLevel
+type filterChange struct {
+	filter  FilterConfig
+	old     loadedFilter
+	running bool
+	logger  ConfigLogger
+}
+
+func (cw *ConfigWatcher) apply(config *Config) error {
+	// create everything that is new before changing anything
+	var errs ConfigErrors
+	var changes []filterChange
+	var created []ConfigLogger
+	// untagged filters are matched to the running ones in order
+	untaggedSeen := 0
+	reused := make(map[int]bool)
+	for _, filter := range config.Filters {
+		if !filter.Enabled {
+			continue
+		}
+		change := filterChange{filter: filter}
+		if filter.Tag != "" {
+			change.old, change.running = cw.loaded[filter.Tag]
+		} else {
+			if untaggedSeen < len(cw.untagged) && cw.untagged[untaggedSeen].filter.Type == filter.Type {
+				change.old, change.running = cw.untagged[untaggedSeen], true
+				reused[untaggedSeen] = true
+			}
+			untaggedSeen++
+		}
+		if change.running && sameDestination(change.old.filter, filter) {
+			formatter, err := newFilterFormatter(filter)
+			if err != nil {
+				errs.addProperty(filter.Tag, "format", err)
+				continue
+			}
+			change.logger.Formatter = formatter
+			change.logger.Filter = newFilterLogFilter(filter)
+			change.logger.RateLimit = filterRateLimit(filter)
+			change.logger.DedupWindow = filterDedup(filter)
+			change.logger.Async = filterAsync(filter)
+			change.logger.StackLevel = filterStackLevel(filter)
+		} else {
+			logger, err := newFilterLogger(filter)
+			if err != nil {
+				errs.addProperty(filter.Tag, "type", err)
+				continue
+			}
+			change.logger = logger
+			created = append(created, logger)
+		}
+		changes = append(changes, change)
+	}
+	if len(errs) > 0 {
+		closeAllWriters(created)
+		return errs
+	}
+
+	loaded := make(map[string]loadedFilter)
+	var untagged []loadedFilter
+	for _, change := range changes {
+		index := change.old.index
+		var err error
+		switch {
+		case !change.running:
+			index, err = cw.add(change.logger)
+		case change.logger.LogWriter == nil:
+			level, granulars := filterLevels(change.filter)
+			changed := change.logger
+			err = cw.t.modifyLogger(index, func(cLog *ConfigLogger) {
+				cLog.Level = level
+				cLog.Granulars = granulars
+				cLog.Formatter = changed.Formatter
+				cLog.Filter = changed.Filter
+				cLog.RateLimit = changed.RateLimit
+				cLog.DedupWindow = changed.DedupWindow
+				cLog.Async = changed.Async
+				cLog.StackLevel = changed.StackLevel
+			})
+		default:
+			err = cw.t.ReplaceLogger(index, change.logger)
+		}
+		if err != nil && change.running {
+			// the logger was removed from under us so start over
+			if change.logger.LogWriter == nil {
+				if change.logger, err = newFilterLogger(change.filter); err != nil {
+					errs.addProperty(change.filter.Tag, "type", err)
+					continue
+				}
+			}
+			index, err = cw.add(change.logger)
+		}
+		if err != nil {
+			errs.add(change.filter.Tag, "type", "%v", err)
+			continue
+		}
+		if change.filter.Tag == "" {
+			untagged = append(untagged, loadedFilter{index, change.filter})
+		} else {
+			loaded[change.filter.Tag] = loadedFilter{index, change.filter}
+		}
+	}
+
+	// remove anything that is gone or disabled
+	for tag, old := range cw.loaded {
+		if _, ok := loaded[tag]; !ok {
+			cw.t.RemoveLogger(old.index)
+		}
+	}
+	for i, old := range cw.untagged {
+		if !reused[i] {
+			cw.t.RemoveLogger(old.index)
+		}
+	}
+	cw.loaded = loaded
+	cw.untagged = untagged
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Add a logger, closing its writer if the Timber was closed in the meantime
+func (cw *ConfigWatcher) add(logger ConfigLogger) (int, error) {
+	index := cw.t.AddLogger(logger)
+	if index < 0 {
+		logger.LogWriter.Close()
+		return index, fmt.Errorf("TIMBER! Can't add a logger after Close")
+	}
+	return index, nil
+}
+
+// Whether two filters write to the same place
+func sameDestination(a, b FilterConfig) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	names, ok := writerProperties[a.Type]
+	if !ok {
+		return reflect.DeepEqual(withoutFormat(a.Properties), withoutFormat(b.Properties))
+	}
+	for _, name := range names {
+		if a.Properties[name] != b.Properties[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func withoutFormat(properties map[string]string) map[string]string {
+	ret := make(map[string]string, len(properties))
+	for name, value := range properties {
+		if name != "format" {
+			ret[name] = value
+		}
+	}
+	return ret
+}
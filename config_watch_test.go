@@ -0,0 +1,239 @@
+package timber
+
+import (
+	"bytes"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigReload(t *testing.T) {
+	var writers []*closeCountWriter
+	RegisterWriterType("watched", func(properties map[string]string) (LogWriter, error) {
+		writer := new(closeCountWriter)
+		writers = append(writers, writer)
+		return writer, nil
+	})
+	filename := filepath.Join(t.TempDir(), "timber.json")
+	write := func(config string) {
+		if err := os.WriteFile(filename, []byte(config), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"filters": [{"enabled": true, "tag": "mem", "type": "watched", "level": "INFO",
+		"properties": [{"name": "dest", "value": "one"}], "format": {"name": "pattern", "value": "a:%M"}}]}`)
+	log := NewTimber()
+	cw, err := log.WatchConfig(filename)
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	defer cw.Stop()
+	log.Debug("dropped")
+	log.Info("first")
+
+	// level and format change in place
+	write(`{"filters": [{"enabled": true, "tag": "mem", "type": "watched", "level": "DEBUG",
+		"properties": [{"name": "dest", "value": "one"}], "format": {"name": "pattern", "value": "b:%M"}}]}`)
+	if err := cw.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	log.Debug("second")
+
+	// invalid config keeps the old one
+	write(`{"filters": [{"enabled": true, "tag": "mem", "type": "watched", "level": "LOUD"}]}`)
+	if err := cw.Reload(); err == nil {
+		t.Errorf("expected reload error")
+	}
+	log.Debug("third")
+
+	// destination change reopens the writer
+	write(`{"filters": [{"enabled": true, "tag": "mem", "type": "watched", "level": "DEBUG",
+		"properties": [{"name": "dest", "value": "two"}]}]}`)
+	if err := cw.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	log.Debug("fourth")
+
+	// disabling removes it
+	write(`{"filters": [{"enabled": false, "tag": "mem", "type": "watched"}]}`)
+	if err := cw.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	log.Info("fifth")
+	log.Close()
+
+	if len(writers) != 2 {
+		t.Fatalf("got %d writers, expected 2", len(writers))
+	}
+	checkMsgs(t, writers[0].msgs, []string{"a:first\n", "b:second\n", "b:third\n"})
+	checkMsgs(t, writers[1].msgs, []string{"fourth\n"})
+	if writers[0].closed != 1 || writers[1].closed != 1 {
+		t.Errorf("close counts %d %d, expected 1 1", writers[0].closed, writers[1].closed)
+	}
+}
+
+func TestWatchConfigUntagged(t *testing.T) {
+	var writers []*closeCountWriter
+	RegisterWriterType("untagged", func(properties map[string]string) (LogWriter, error) {
+		writer := new(closeCountWriter)
+		writers = append(writers, writer)
+		return writer, nil
+	})
+	filename := filepath.Join(t.TempDir(), "timber.json")
+	write := func(config string) {
+		if err := os.WriteFile(filename, []byte(config), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"filters": [{"enabled": true, "type": "untagged", "level": "INFO"},
+		{"enabled": true, "type": "console", "level": "CRITICAL"}]}`)
+	log := NewTimber()
+	cw, err := log.WatchConfig(filename)
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	defer cw.Stop()
+	log.Info("first")
+
+	// matched by position and type so the writer stays open
+	write(`{"filters": [{"enabled": true, "type": "untagged", "level": "DEBUG"},
+		{"enabled": true, "type": "console", "level": "CRITICAL"}]}`)
+	if err := cw.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	log.Debug("second")
+
+	// a different type in its place replaces it
+	write(`{"filters": [{"enabled": true, "type": "console", "level": "CRITICAL"}]}`)
+	if err := cw.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	log.Info("third")
+	log.Close()
+
+	if len(writers) != 1 || writers[0].closed != 1 {
+		t.Fatalf("got %d writers, expected 1 closed once", len(writers))
+	}
+	checkMsgs(t, writers[0].msgs, []string{"first\n", "second\n"})
+	if err := cw.Reload(); err == nil {
+		t.Errorf("expected reload error after Close")
+	}
+}
+
+func TestWatchConfigMissing(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "timber.json")
+	config := `{"filters": [{"enabled": true, "tag": "out", "type": "console", "level": "CRITICAL"}]}`
+	if err := os.WriteFile(filename, []byte(config), 0666); err != nil {
+		t.Fatal(err)
+	}
+	log := NewTimber()
+	defer log.Close()
+	cw, err := log.WatchConfig(filename)
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	cw.Stop()
+	if cw.changed() {
+		t.Errorf("changed without changes")
+	}
+
+	// a missing file is reloaded and reported once
+	os.Remove(filename)
+	if !cw.changed() {
+		t.Errorf("missing file not noticed")
+	}
+	if err := cw.Reload(); err == nil {
+		t.Errorf("expected reload error for missing file")
+	}
+	if cw.changed() {
+		t.Errorf("missing file reloaded again")
+	}
+	if err := os.WriteFile(filename, []byte(config), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if !cw.changed() {
+		t.Errorf("restored file not noticed")
+	}
+}
+
+// Collects the standard log output of the watch goroutine
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) count(s string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return strings.Count(b.buf.String(), s)
+}
+
+func TestWatchConfigPolling(t *testing.T) {
+	defer func(interval time.Duration) { DefaultConfigPollInterval = interval }(DefaultConfigPollInterval)
+	DefaultConfigPollInterval = 10 * time.Millisecond
+	output := new(lockedBuffer)
+	stdlog.SetOutput(output)
+	defer stdlog.SetOutput(os.Stderr)
+	waitFor := func(what string, done func() bool) {
+		for deadline := time.Now().Add(5 * time.Second); !done(); time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+		}
+	}
+
+	filename := filepath.Join(t.TempDir(), "timber.json")
+	write := func(level string) {
+		config := `{"filters": [{"enabled": true, "tag": "out", "type": "console", "level": "` + level + `"}]}`
+		if err := os.WriteFile(filename, []byte(config), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("INFO")
+	log := NewTimber()
+	defer log.Close()
+	cw, err := log.WatchConfig(filename)
+	if err != nil {
+		t.Fatalf("WatchConfig: %v", err)
+	}
+	defer cw.Stop()
+
+	write("DEBUG")
+	waitFor("the level change", func() bool { return log.IsEnabled(DEBUG) })
+
+	// a bad file is reported once however often it's reloaded
+	write("LOUD")
+	waitFor("the reload error", func() bool { return output.count("failed") == 1 })
+	for i := 1; i <= 3; i++ {
+		modTime := time.Now().Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		waitFor("the reload", func() bool {
+			cw.lock.Lock()
+			defer cw.lock.Unlock()
+			return cw.modTime.Equal(modTime)
+		})
+	}
+	if n := output.count("failed"); n != 1 || !log.IsEnabled(DEBUG) || log.IsEnabled(FINE) {
+		t.Errorf("got %d reports, expected 1 and the old config", n)
+	}
+
+	// and again once it was fixed in between
+	write("FINE")
+	waitFor("the fixed config", func() bool { return log.IsEnabled(FINE) })
+	write("LOUD")
+	waitFor("the second report", func() bool { return output.count("failed") == 2 })
+}
@@ -3,8 +3,7 @@ package timber
 import (
 	"encoding/xml"
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"reflect"
 )
 
@@ -20,6 +19,51 @@ type XMLProperty struct {
 	Name  string `xml:"name,attr"`
 	Value string `xml:",chardata"`
 }
+
+// Limits the records written by a filter, every element that is set must match:
+//   <include>
+//     <message>payment|charge</message>
+//     <source>payments/*.go</source>
+//     <field name="service">billing</field>
+//   </include>
+type XMLMatch struct {
+	Message string        `xml:"message"`
+	Source  string        `xml:"source"`
+	Fields  []XMLProperty `xml:"field"`
+}
+
+// Rate limits and samples the records written by a filter, every element is optional:
+//   <ratelimit>
+//     <rate>10</rate>                         records per second for each level
+//     <burst>50</burst>
+//     <first>5</first>                        of each message per sample interval
+//     <thereafter>100</thereafter>
+//     <sampleinterval>1s</sampleinterval>
+//     <summaryinterval>1m</summaryinterval>   how often to report dropped records
+//   </ratelimit>
+type XMLRateLimit struct {
+	Rate            string `xml:"rate"`
+	Burst           string `xml:"burst"`
+	First           string `xml:"first"`
+	Thereafter      string `xml:"thereafter"`
+	SampleInterval  string `xml:"sampleinterval"`
+	SummaryInterval string `xml:"summaryinterval"`
+}
+
+// Writes the records of a filter on its own goroutine, every element is optional:
+//   <async>
+//     <buffersize>1000</buffersize>
+//     <overflow>dropbelowlevel</overflow>     block, dropnewest, dropoldest or dropbelowlevel
+//     <droplevel>WARNING</droplevel>
+//     <dropreportinterval>1m</dropreportinterval>
+//   </async>
+type XMLAsync struct {
+	BufferSize         string `xml:"buffersize"`
+	Overflow           string `xml:"overflow"`
+	DropLevel          string `xml:"droplevel"`
+	DropReportInterval string `xml:"dropreportinterval"`
+}
+
 type XMLFilter struct {
 	XMLName    xml.Name      `xml:"filter"`
 	Enabled    bool          `xml:"enabled,attr"`
@@ -29,6 +73,12 @@ type XMLFilter struct {
 	Format     XMLProperty   `xml:"format"`
 	Properties []XMLProperty `xml:"property"`
 	Granulars  []XMLGranular `xml:"granular"`
+	Includes   []XMLMatch    `xml:"include"`
+	Excludes   []XMLMatch    `xml:"exclude"`
+	RateLimit  *XMLRateLimit `xml:"ratelimit"`
+	Dedup      string        `xml:"dedup"`
+	Async      *XMLAsync     `xml:"async"`
+	StackLevel string        `xml:"stacklevel"`
 }
 
 type XMLConfig struct {
@@ -38,106 +88,67 @@ type XMLConfig struct {
 
 // Loads the configuration from an XML file (as you were probably expecting)
 func (t *Timber) LoadXMLConfig(filename string) error {
-	if len(filename) <= 0 {
-		return fmt.Errorf("Empty filename")
-	}
-
-	file, err := os.Open(filename)
+	config, err := readConfigFile(filename, "xml")
 	if err != nil {
-		return fmt.Errorf("TIMBER! Can't load xml config file: %s %v", filename, err)
+		return err
 	}
-	defer file.Close()
+	return t.LoadConfigModel(config)
+}
 
+// Parse an XML config into the shared model without loading it
+func ReadXMLConfig(r io.Reader) (*Config, error) {
 	config := XMLConfig{}
-	err = xml.NewDecoder(file).Decode(&config)
-	if err != nil {
-		return fmt.Errorf("TIMBER! Can't parse xml config file: %s %v", filename, err)
-	}
-
-	for _, filter := range config.Filters {
-		if !filter.Enabled {
-			continue
-		}
-		level := getLevel(filter.Level)
-		formatter := getXMLFormatter(filter)
-		granulars := make(map[string]Level)
-		for _, granular := range filter.Granulars {
-			granulars[granular.Path] = getLevel(granular.Level)
-		}
-		configLogger := ConfigLogger{Level: level, Formatter: formatter, Granulars: granulars}
-
-		var err error
-		switch filter.Type {
-		case "console":
-			configLogger.LogWriter = new(ConsoleWriter)
-		case "socket":
-			if configLogger.LogWriter, err = getXMLSocketWriter(filter); err != nil {
-				return err
-			}
-		case "file":
-			if configLogger.LogWriter, err = getXMLFileWriter(filter); err != nil {
-				return err
-			}
-		default:
-			log.Printf("TIMBER! Warning unrecognized filter in config file: %v\n", filter.Tag)
-			continue
-		}
-
-		t.AddLogger(configLogger)
+	if err := xml.NewDecoder(r).Decode(&config); err != nil {
+		return nil, fmt.Errorf("TIMBER! Can't parse xml config: %v", err)
 	}
-	return nil
+	return config.Config(), nil
 }
 
-func getXMLFormatter(filter XMLFilter) LogFormatter {
-	format := ""
-	property := XMLProperty{}
-
-	// If format field is set then use it's value, otherwise
-	// attempt to get the format field from the filters properties
-	if !reflect.DeepEqual(filter.Format, property) {
-		format = filter.Format.Value
-	} else {
+// Convert to the shared config model.  If the format element is set then its
+// value is the "format" property, otherwise the format property is used
+func (c XMLConfig) Config() *Config {
+	config := &Config{Filters: make([]FilterConfig, 0, len(c.Filters))}
+	for _, filter := range c.Filters {
+		properties := make(map[string]string)
 		for _, prop := range filter.Properties {
-			if prop.Name == "format" {
-				format = prop.Value
-			}
+			properties[prop.Name] = prop.Value
 		}
-	}
-
-	// If empty format set the default as just the message
-	if format == "" {
-		format = "%M"
-	}
-	return NewPatFormatter(format)
-}
-
-func getXMLSocketWriter(filter XMLFilter) (LogWriter, error) {
-	var protocol, endpoint string
-
-	for _, property := range filter.Properties {
-		if property.Name == "protocol" {
-			protocol = property.Value
-		} else if property.Name == "endpoint" {
-			endpoint = property.Value
+		format := ""
+		if !reflect.DeepEqual(filter.Format, XMLProperty{}) {
+			properties["format"] = filter.Format.Value
+			format = filter.Format.Name
 		}
+		granulars := make([]GranularConfig, 0, len(filter.Granulars))
+		for _, granular := range filter.Granulars {
+			granulars = append(granulars, GranularConfig{Level: granular.Level, Path: granular.Path})
+		}
+		config.Filters = append(config.Filters, FilterConfig{
+			Enabled:    filter.Enabled,
+			Tag:        filter.Tag,
+			Type:       filter.Type,
+			Level:      filter.Level,
+			Format:     format,
+			Properties: properties,
+			Granulars:  granulars,
+			Includes:   xmlMatches(filter.Includes),
+			Excludes:   xmlMatches(filter.Excludes),
+			RateLimit:  (*RateLimitConfig)(filter.RateLimit),
+			Dedup:      filter.Dedup,
+			Async:      (*AsyncConfig)(filter.Async),
+			StackLevel: filter.StackLevel,
+		})
 	}
-
-	if protocol == "" || endpoint == "" {
-		return nil, fmt.Errorf("TIMBER! Missing protocol or endpoint for socket log writer")
-	}
-	return NewSocketWriter(protocol, endpoint)
+	return config
 }
 
-func getXMLFileWriter(filter XMLFilter) (LogWriter, error) {
-	filename := ""
-
-	for _, property := range filter.Properties {
-		if property.Name == "filename" {
-			filename = property.Value
+func xmlMatches(matches []XMLMatch) []MatchConfig {
+	ret := make([]MatchConfig, 0, len(matches))
+	for _, match := range matches {
+		fields := make(map[string]string)
+		for _, field := range match.Fields {
+			fields[field.Name] = field.Value
 		}
+		ret = append(ret, MatchConfig{Message: match.Message, Source: match.Source, Fields: fields})
 	}
-	if filename == "" {
-		return nil, fmt.Errorf("TIMBER! Missing filename for file log writer")
-	}
-	return NewFileWriter(filename)
+	return ret
 }
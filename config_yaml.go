@@ -0,0 +1,36 @@
+package timber
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parse a YAML config into the shared model without loading it.
+// The structure is the same as the JSON config:
+//   filters:
+//     - enabled: true
+//       tag: stdout
+//       type: console
+//       level: DEBUG
+//       format:
+//         name: pattern
+//         value: "[%D %T] %L %M"
+//       properties:
+//         - name: filename
+//           value: log/server.log
+//       granulars:
+//         - level: INFO
+//           path: path/to/package
+func ReadYAMLConfig(r io.Reader) (*Config, error) {
+	tree := make(map[string]interface{})
+	if err := yaml.NewDecoder(r).Decode(&tree); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("TIMBER! Can't parse yaml config: %v", err)
+	}
+	config, err := configFromTree(tree)
+	if err != nil {
+		return nil, fmt.Errorf("TIMBER! Can't parse yaml config: %v", err)
+	}
+	return config, nil
+}
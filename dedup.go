@@ -0,0 +1,65 @@
+package timber
+
+import (
+	"fmt"
+	"time"
+)
+
+// Collapses consecutive identical records for a ConfigLogger like syslogd.  The
+// first record is written and the repeats are counted until a different record
+// arrives or the window since the last write expires, then a "last message repeated
+// N times" record is written.  Only used by the dispatch goroutine
+type deduper struct {
+	window  time.Duration
+	last    *LogRecord // the last record written
+	start   time.Time  // when the current window started
+	repeats int
+}
+
+func newDeduper(window time.Duration) *deduper {
+	if window <= 0 {
+		return nil
+	}
+	return &deduper{window: window}
+}
+
+// Whether rec repeats the last record and shouldn't be written.  If a run of
+// repeats just ended the "repeated" record to write first is returned too
+func (d *deduper) check(rec *LogRecord) (bool, *LogRecord) {
+	if d == nil {
+		return false, nil
+	}
+	if d.last != nil && sameRecord(d.last, rec) && rec.Timestamp.Sub(d.start) < d.window {
+		d.repeats++
+		return true, nil
+	}
+	repeated := d.flush(rec.Timestamp, true)
+	d.last = rec
+	d.start = rec.Timestamp
+	return false, repeated
+}
+
+// The "repeated" record if there were repeats and the window expired or force is set.
+// The last record is kept so repeats after the flush are still collapsed
+func (d *deduper) flush(now time.Time, force bool) *LogRecord {
+	if d == nil || d.repeats == 0 || !force && now.Sub(d.start) < d.window {
+		return nil
+	}
+	rec := *d.last
+	rec.Timestamp = now
+	rec.Message = fmt.Sprintf("last message repeated %d times", d.repeats)
+	rec.Template = "last message repeated %d times"
+	rec.Fields = []Field{{"repeated", d.repeats}}
+	// the original record already had them
+	rec.Error = nil
+	rec.Stack = nil
+	rec.keepStack = false
+	d.repeats = 0
+	d.start = now
+	return &rec
+}
+
+func sameRecord(a, b *LogRecord) bool {
+	return a.Level == b.Level && a.SourceLine == b.SourceLine && a.SourceFile == b.SourceFile &&
+		a.Message == b.Message
+}
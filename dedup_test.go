@@ -0,0 +1,94 @@
+package timber
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeduper(t *testing.T) {
+	start := time.Unix(1000, 0)
+	rec := func(msg string, offset time.Duration) *LogRecord {
+		return &LogRecord{Level: ERROR, Message: msg, SourceFile: "a.go", SourceLine: 1, Timestamp: start.Add(offset),
+			Error: timeoutError{}, Stack: []StackFrame{{"main.main", "main.go", 3}}}
+	}
+	d := newDeduper(time.Minute)
+	var out []string
+	write := func(r *LogRecord) {
+		repeat, repeated := d.check(r)
+		if repeated != nil {
+			if repeated.Error != nil || repeated.Stack != nil {
+				t.Errorf("%q repeats the error or stack", repeated.Message)
+			}
+			out = append(out, repeated.Message)
+		}
+		if !repeat {
+			out = append(out, r.Message)
+		}
+	}
+	write(rec("down", 0))
+	write(rec("down", time.Second))
+	write(rec("down", 2*time.Second))
+	write(rec("up", 3*time.Second))
+	write(rec("up", 4*time.Second))
+	write(rec("down", 5*time.Second))
+	if r := d.flush(start.Add(10*time.Second), false); r != nil {
+		t.Errorf("flushed before the window expired: %q", r.Message)
+	}
+	write(rec("down", 6*time.Second))
+	if r := d.flush(start.Add(65*time.Second), false); r != nil {
+		out = append(out, r.Message)
+	}
+	// the window restarts so this is still a repeat
+	write(rec("down", 70*time.Second))
+	if r := d.flush(start.Add(71*time.Second), true); r != nil {
+		out = append(out, r.Message)
+	}
+	checkMsgs(t, out, []string{
+		"down",
+		"last message repeated 2 times",
+		"up",
+		"last message repeated 1 times",
+		"down",
+		"last message repeated 1 times",
+		"last message repeated 1 times",
+	})
+}
+
+func TestConfigDedup(t *testing.T) {
+	writer := new(memWriter)
+	RegisterWriterType("dedup", func(properties map[string]string) (LogWriter, error) {
+		return writer, nil
+	})
+	config := `{"filters": [{"enabled": true, "tag": "dedup", "type": "dedup", "level": "INFO",
+		"dedup": "1h", "format": {"name": "pattern", "value": "%L %M"}}]}`
+	loaded, err := ReadConfig(strings.NewReader(config), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := NewTimber()
+	if err := log.LoadConfigModel(loaded); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 8; i++ {
+		if i == 5 {
+			log.Info("retrying")
+		}
+		log.Error("connection refused")
+	}
+	log.Error("connection refused") // a different line
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{
+		"EROR connection refused\n",
+		"EROR last message repeated 4 times\n",
+		"INFO retrying\n",
+		"EROR connection refused\n",
+		"EROR last message repeated 2 times\n",
+		"EROR connection refused\n",
+	})
+
+	bad := &Config{Filters: []FilterConfig{{Enabled: true, Tag: "bad", Type: "console", Level: "INFO", Dedup: "0s"}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), `bad duration "0s"`) {
+		t.Errorf("got %v, expected bad duration", err)
+	}
+}
@@ -0,0 +1,102 @@
+package timber
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The error returned by Warn, Error and Critical.  Its message is the logged message
+// and it wraps the error that was logged, if any, so errors.Is and errors.As work
+type loggedError struct {
+	msg string
+	err error
+}
+
+func (e *loggedError) Error() string { return e.msg }
+func (e *loggedError) Unwrap() error { return e.err }
+
+// The error returned by Warn, Error and Critical when the level is disabled.  The message
+// is only formatted, and a func() string only called, when Error is
+type unloggedError struct {
+	arg0 interface{}
+	args []interface{}
+}
+
+func (e *unloggedError) Error() string {
+	_, msg, _ := formatArgs(e.arg0, e.args)
+	return msg
+}
+
+func (e *unloggedError) Unwrap() error {
+	switch first := e.arg0.(type) {
+	case func() string:
+		return nil
+	case error:
+		return first
+	}
+	return firstError(e.args)
+}
+
+// Format a message that may wrap errors with %w, which only fmt.Errorf understands
+func formatMessage(format string, args []interface{}) string {
+	if strings.Contains(format, "%w") {
+		return fmt.Errorf(format, args...).Error()
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// The first non-nil error in args
+func firstError(args []interface{}) error {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The first non-nil error value in fields
+func firstFieldError(fields []Field) error {
+	for _, field := range fields {
+		if err, ok := field.Value.(error); ok && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Longest chain of wrapped errors followed, in case an error wraps itself
+const maxErrorChain = 32
+
+// The error followed by the errors it wraps, outermost first.  Errors that wrap several
+// with Unwrap() []error are followed depth first
+func errorChain(err error) []error {
+	var chain []error
+	var follow func(err error)
+	follow = func(err error) {
+		for err != nil && len(chain) < maxErrorChain {
+			chain = append(chain, err)
+			if multi, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, wrapped := range multi.Unwrap() {
+					follow(wrapped)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	follow(err)
+	return chain
+}
+
+// Render the chain of an error as type: message pairs joined by " <- "
+//   *fs.PathError: open x: no such file or directory <- syscall.Errno: no such file or directory
+func formatErrorChain(err error) string {
+	chain := errorChain(err)
+	parts := make([]string, 0, len(chain))
+	for _, e := range chain {
+		parts = append(parts, fmt.Sprintf("%T: %s", e, e.Error()))
+	}
+	return strings.Join(parts, " <- ")
+}
@@ -0,0 +1,64 @@
+package timber
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+type multiError []error
+
+func (m multiError) Error() string   { return "several" }
+func (m multiError) Unwrap() []error { return m }
+
+func TestLoggedErrors(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%L %M | %E")})
+	pathErr := &fs.PathError{Op: "open", Path: "users.db", Err: fs.ErrNotExist}
+
+	err := log.Error("load user %d: %w", 7, pathErr)
+	if err.Error() != "load user 7: open users.db: file does not exist" {
+		t.Errorf("got message %q", err.Error())
+	}
+	var target *fs.PathError
+	if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &target) || target != pathErr {
+		t.Errorf("returned error doesn't wrap the logged error: %#v", err)
+	}
+	if err := log.Warn(pathErr); errors.Unwrap(err) != pathErr {
+		t.Errorf("got %#v, expected the logged error to be wrapped", err)
+	}
+	if err := log.Criticalw("giving up", "attempts", 3, "err", pathErr); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %#v, expected the field error to be wrapped", err)
+	}
+	if err := log.Error("plain"); errors.Unwrap(err) != nil {
+		t.Errorf("got %#v, expected nothing wrapped", err)
+	}
+	log.Close()
+
+	chain := "*fs.PathError: open users.db: file does not exist <- *errors.errorString: file does not exist"
+	checkMsgs(t, writer.msgs, []string{
+		"EROR load user 7: open users.db: file does not exist | " + chain + "\n",
+		"WARN open users.db: file does not exist | " + chain + "\n",
+		"CRIT giving up | " + chain + "\n",
+		"EROR plain | \n",
+	})
+}
+
+func TestErrorChain(t *testing.T) {
+	inner := errors.New("inner")
+	err := multiError{&fs.PathError{Op: "read", Path: "a", Err: inner}, errors.New("other")}
+	var msgs []string
+	for _, e := range errorChain(err) {
+		msgs = append(msgs, e.Error())
+	}
+	checkMsgs(t, msgs, []string{"several", "read a: inner", "inner", "other"})
+
+	rec := *lr
+	rec.Error = &fs.PathError{Op: "read", Path: "a", Err: inner}
+	jf := &JSONFormatter{TimeLayout: "2006"}
+	out := `{"time":"` + rec.Timestamp.Format("2006") + `","level":"INFO","msg":"` + rec.Message + `",` +
+		`"source":"/blah/der/some_file.go:7","func":"hi.Zoot","package":"hi","error_chain":[` +
+		`{"type":"*fs.PathError","msg":"read a: inner"},{"type":"*errors.errorString","msg":"inner"}]}` + "\n"
+	verify(t, "json error", jf.Format(&rec), out)
+}
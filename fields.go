@@ -0,0 +1,167 @@
+package timber
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A single key/value pair attached to a LogRecord
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Key used for a trailing value that has no key
+const MissingKey = "!MISSING"
+
+// Structured logging with key/value pairs in addition to the message.
+// The keysAndValues are alternating keys and values, e.g.:
+//   t.Infow("request done", "user", id, "latency", d)
+// Keys that are not strings are converted with fmt.Sprint
+type FieldLogger interface {
+	Finestw(msg string, keysAndValues ...interface{})
+	Finew(msg string, keysAndValues ...interface{})
+	Debugw(msg string, keysAndValues ...interface{})
+	Tracew(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{}) error
+	Errorw(msg string, keysAndValues ...interface{}) error
+	Criticalw(msg string, keysAndValues ...interface{}) error
+	Logw(lvl Level, msg string, keysAndValues ...interface{})
+}
+
+// Pair up alternating keys and values into Fields
+func makeFields(keysAndValues []interface{}) []Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, Field{MissingKey, keysAndValues[i]})
+			break
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		fields = append(fields, Field{key, keysAndValues[i+1]})
+	}
+	return fields
+}
+
+// Render fields as space separated key=value pairs.  Values containing spaces,
+// quotes, '=' or control characters are quoted and keys are made safe with fieldKey
+func formatFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	buf := new(bytes.Buffer)
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(fieldKey(field.Key))
+		buf.WriteByte('=')
+		buf.WriteString(quoteFieldValue(fmt.Sprint(field.Value)))
+	}
+	return buf.String()
+}
+
+// Quote values that would otherwise not read back as a single value
+func quoteFieldValue(value string) string {
+	if value == "" || !utf8.ValidString(value) || strings.IndexFunc(value, needsQuote) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func needsQuote(r rune) bool {
+	return r == ' ' || r == '=' || r == '"' || !unicode.IsPrint(r)
+}
+
+// Keys can't be quoted so the characters that would end them early are replaced with '_'
+func fieldKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	if utf8.ValidString(key) && strings.IndexFunc(key, needsQuote) < 0 {
+		return key
+	}
+	return strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || needsQuote(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// Like logArgs for the FieldLogger methods.  Returns the logged error, the first error value
+func (t *Timber) logFields(lvl Level, msg string, keysAndValues []interface{}, depth int) error {
+	fields := makeFields(keysAndValues)
+	err := firstFieldError(fields)
+	t.prepareAndSendFields(lvl, msg, msg, fields, err, nil, depth+1)
+	return err
+}
+
+// FieldLogger interface
+func (t *Timber) Finestw(msg string, keysAndValues ...interface{}) {
+	t.logFields(FINEST, msg, keysAndValues, t.FileDepth)
+}
+func (t *Timber) Finew(msg string, keysAndValues ...interface{}) {
+	t.logFields(FINE, msg, keysAndValues, t.FileDepth)
+}
+func (t *Timber) Debugw(msg string, keysAndValues ...interface{}) {
+	t.logFields(DEBUG, msg, keysAndValues, t.FileDepth)
+}
+func (t *Timber) Tracew(msg string, keysAndValues ...interface{}) {
+	t.logFields(TRACE, msg, keysAndValues, t.FileDepth)
+}
+func (t *Timber) Infow(msg string, keysAndValues ...interface{}) {
+	t.logFields(INFO, msg, keysAndValues, t.FileDepth)
+}
+func (t *Timber) Warnw(msg string, keysAndValues ...interface{}) error {
+	return &loggedError{msg, t.logFields(WARNING, msg, keysAndValues, t.FileDepth)}
+}
+func (t *Timber) Errorw(msg string, keysAndValues ...interface{}) error {
+	return &loggedError{msg, t.logFields(ERROR, msg, keysAndValues, t.FileDepth)}
+}
+func (t *Timber) Criticalw(msg string, keysAndValues ...interface{}) error {
+	return &loggedError{msg, t.logFields(CRITICAL, msg, keysAndValues, t.FileDepth)}
+}
+func (t *Timber) Logw(lvl Level, msg string, keysAndValues ...interface{}) {
+	t.logFields(lvl, msg, keysAndValues, t.FileDepth)
+}
+
+// Simple wrappers for FieldLogger interface
+func Finestw(msg string, keysAndValues ...interface{}) {
+	Global.logFields(FINEST, msg, keysAndValues, Global.FileDepth)
+}
+func Finew(msg string, keysAndValues ...interface{}) {
+	Global.logFields(FINE, msg, keysAndValues, Global.FileDepth)
+}
+func Debugw(msg string, keysAndValues ...interface{}) {
+	Global.logFields(DEBUG, msg, keysAndValues, Global.FileDepth)
+}
+func Tracew(msg string, keysAndValues ...interface{}) {
+	Global.logFields(TRACE, msg, keysAndValues, Global.FileDepth)
+}
+func Infow(msg string, keysAndValues ...interface{}) {
+	Global.logFields(INFO, msg, keysAndValues, Global.FileDepth)
+}
+func Warnw(msg string, keysAndValues ...interface{}) error {
+	return &loggedError{msg, Global.logFields(WARNING, msg, keysAndValues, Global.FileDepth)}
+}
+func Errorw(msg string, keysAndValues ...interface{}) error {
+	return &loggedError{msg, Global.logFields(ERROR, msg, keysAndValues, Global.FileDepth)}
+}
+func Criticalw(msg string, keysAndValues ...interface{}) error {
+	return &loggedError{msg, Global.logFields(CRITICAL, msg, keysAndValues, Global.FileDepth)}
+}
+func Logw(lvl Level, msg string, keysAndValues ...interface{}) {
+	Global.logFields(lvl, msg, keysAndValues, Global.FileDepth)
+}
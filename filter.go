@@ -0,0 +1,99 @@
+package timber
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Decides which records a ConfigLogger writes.  It is checked after the level
+// and granulars so it only sees records that would otherwise be written
+type LogFilter interface {
+	Allow(rec *LogRecord) bool
+}
+
+// Use a plain function as a LogFilter
+type LogFilterFunc func(rec *LogRecord) bool
+
+func (f LogFilterFunc) Allow(rec *LogRecord) bool {
+	return f(rec)
+}
+
+// Records with a message matching re
+func MessageFilter(re *regexp.Regexp) LogFilter {
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		return re.MatchString(rec.Message)
+	})
+}
+
+// Records with a field named key whose value prints the same as value with
+// fmt.Sprint, so FieldFilter("user", "7") matches a field of int 7
+func FieldFilter(key string, value interface{}) LogFilter {
+	want := fmt.Sprint(value)
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		for _, field := range rec.Fields {
+			if field.Key == key && fmt.Sprint(field.Value) == want {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Records logged from a source file matching pattern, using path.Match syntax.
+// A pattern without a '/' is matched against the file name, otherwise it is
+// matched against as many trailing directories as it has: payments/*.go
+// matches /src/github.com/us/svc/payments/charge.go
+func SourceFilter(pattern string) LogFilter {
+	elems := strings.Count(pattern, "/") + 1
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		file := rec.SourceFile
+		start := len(file)
+		for i := 0; i < elems && start >= 0; i++ {
+			start = strings.LastIndex(file[:start], "/")
+		}
+		matched, _ := path.Match(pattern, file[start+1:])
+		return matched
+	})
+}
+
+// Records allowed by every filter
+func AllFilters(filters ...LogFilter) LogFilter {
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		for _, filter := range filters {
+			if !filter.Allow(rec) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Records allowed by at least one filter
+func AnyFilter(filters ...LogFilter) LogFilter {
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		for _, filter := range filters {
+			if filter.Allow(rec) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Records not allowed by filter
+func NotFilter(filter LogFilter) LogFilter {
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		return !filter.Allow(rec)
+	})
+}
+
+// Records allowed by any of the includes, or all records if there are none,
+// and by none of the excludes
+func IncludeExcludeFilter(includes, excludes []LogFilter) LogFilter {
+	include, exclude := AnyFilter(includes...), AnyFilter(excludes...)
+	return LogFilterFunc(func(rec *LogRecord) bool {
+		return (len(includes) == 0 || include.Allow(rec)) && !exclude.Allow(rec)
+	})
+}
@@ -0,0 +1,90 @@
+package timber
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLogFilters(t *testing.T) {
+	rec := &LogRecord{
+		Message:    "payment declined",
+		SourceFile: "/src/github.com/us/svc/payments/charge.go",
+		Fields:     []Field{{"service", "payments"}, {"attempt", 2}},
+	}
+	tests := []struct {
+		name   string
+		filter LogFilter
+		allow  bool
+	}{
+		{"message", MessageFilter(regexp.MustCompile("^payment")), true},
+		{"other message", MessageFilter(regexp.MustCompile("refund")), false},
+		{"field", FieldFilter("service", "payments"), true},
+		{"field number", FieldFilter("attempt", "2"), true},
+		{"other field", FieldFilter("service", "billing"), false},
+		{"file", SourceFilter("charge.go"), true},
+		{"file glob", SourceFilter("*.go"), true},
+		{"dir", SourceFilter("payments/*.go"), true},
+		{"other dir", SourceFilter("billing/*.go"), false},
+		{"absolute", SourceFilter("/src/github.com/us/svc/payments/charge.go"), true},
+		{"too long", SourceFilter("/x/src/github.com/us/svc/payments/charge.go"), false},
+		{"all", AllFilters(FieldFilter("service", "payments"), SourceFilter("charge.go")), true},
+		{"not all", AllFilters(FieldFilter("service", "payments"), SourceFilter("refund.go")), false},
+		{"any", AnyFilter(FieldFilter("service", "billing"), SourceFilter("charge.go")), true},
+		{"not", NotFilter(SourceFilter("charge.go")), false},
+		{"include", IncludeExcludeFilter([]LogFilter{FieldFilter("attempt", 2)}, nil), true},
+		{"exclude", IncludeExcludeFilter(nil, []LogFilter{FieldFilter("attempt", 2)}), false},
+		{"neither", IncludeExcludeFilter(nil, nil), true},
+	}
+	for _, test := range tests {
+		if allow := test.filter.Allow(rec); allow != test.allow {
+			t.Errorf("%s: got %v, expected %v", test.name, allow, test.allow)
+		}
+	}
+}
+
+func TestConfigIncludeExclude(t *testing.T) {
+	writer := new(memWriter)
+	RegisterWriterType("audit", func(properties map[string]string) (LogWriter, error) {
+		return writer, nil
+	})
+	config := `<logging>
+  <filter enabled="true">
+    <tag>audit</tag>
+    <type>audit</type>
+    <level>ERROR</level>
+    <include>
+      <message>(?i)payment</message>
+    </include>
+    <include>
+      <field name="service">payments</field>
+    </include>
+    <exclude>
+      <message>test card</message>
+    </exclude>
+  </filter>
+</logging>`
+	loaded, err := ReadConfig(strings.NewReader(config), "xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := NewTimber()
+	if err := log.LoadConfigModel(loaded); err != nil {
+		t.Fatal(err)
+	}
+	log.Error("Payment declined")
+	log.Error("disk full")
+	log.Errorw("declined", "service", "payments")
+	log.Errorw("declined", "service", "billing")
+	log.Error("payment declined for test card")
+	log.Info("payment ok")
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"Payment declined\n", "declined\n"})
+
+	bad := &Config{Filters: []FilterConfig{{Enabled: true, Tag: "bad", Type: "console", Level: "INFO",
+		Includes: []MatchConfig{{}, {Message: "(", Source: "["}}}}}
+	errs, _ := bad.Validate().(ConfigErrors)
+	if len(errs) != 3 || errs[0].Field != "include" || errs[1].Field != "include message" || errs[2].Field != "include source" {
+		t.Errorf("got %v, expected empty include, bad message and bad source", errs)
+	}
+}
@@ -0,0 +1,120 @@
+package timber
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Granular paths are compiled once when the logger is added.  A path matches:
+//   - the same function path, package path or logger name
+//   - anything below it: github.com/us/svc covers github.com/us/svc/db and
+//     github.com/us/svc.Handler (github.com/us/svc/... is the same thing)
+//   - a glob where * matches any characters and ? matches one: *.handleRequest
+// The longest matching path wins, except that a path naming the exact function
+// beats a logger name which beats everything else.
+type granularMatcher struct {
+	entries []granularEntry // longest first
+	// results by function path and name so each call site is only matched once.
+	// Names may be made per request so it's emptied once it holds maxGranularCache
+	cache map[granularKey]granularResult
+}
+
+// Most call site and name results a granularMatcher keeps
+const maxGranularCache = 4096
+
+type granularEntry struct {
+	path  string
+	glob  *regexp.Regexp // nil for prefix paths
+	level Level
+}
+
+type granularKey struct {
+	funcPath, packagePath, name string
+}
+
+type granularResult struct {
+	level Level
+	ok    bool
+}
+
+func compileGranulars(granulars map[string]Level) *granularMatcher {
+	if len(granulars) == 0 {
+		return nil
+	}
+	m := &granularMatcher{cache: make(map[granularKey]granularResult)}
+	for path, level := range granulars {
+		entry := granularEntry{path: strings.TrimSuffix(path, "/..."), level: level}
+		if strings.ContainsAny(path, "*?") {
+			entry.path = path
+			pattern := regexp.QuoteMeta(path)
+			pattern = strings.Replace(pattern, `\*`, `.*`, -1)
+			pattern = strings.Replace(pattern, `\?`, `.`, -1)
+			entry.glob = regexp.MustCompile("^" + pattern + "$")
+		}
+		m.entries = append(m.entries, entry)
+	}
+	sort.Slice(m.entries, func(i, j int) bool {
+		if len(m.entries[i].path) != len(m.entries[j].path) {
+			return len(m.entries[i].path) > len(m.entries[j].path)
+		}
+		// exact paths beat globs of the same length
+		return m.entries[i].glob == nil && m.entries[j].glob != nil
+	})
+	return m
+}
+
+// The granular level for the record if any path matches
+func (m *granularMatcher) level(rec *LogRecord) (Level, bool) {
+	if m == nil {
+		return NONE, false
+	}
+	key := granularKey{rec.FuncPath, rec.PackagePath, rec.Name}
+	if res, ok := m.cache[key]; ok {
+		return res.level, res.ok
+	}
+	res := m.match(rec)
+	if len(m.cache) >= maxGranularCache {
+		m.cache = make(map[granularKey]granularResult)
+	}
+	m.cache[key] = res
+	return res.level, res.ok
+}
+
+func (m *granularMatcher) match(rec *LogRecord) granularResult {
+	var best *granularEntry
+	for i := range m.entries {
+		if m.entries[i].matches(rec.FuncPath) || m.entries[i].matches(rec.PackagePath) {
+			best = &m.entries[i]
+			break
+		}
+	}
+	if best != nil && best.glob == nil && best.path == rec.FuncPath {
+		return granularResult{best.level, true}
+	}
+	if rec.Name != "" {
+		for _, entry := range m.entries {
+			if entry.matches(rec.Name) {
+				return granularResult{entry.level, true}
+			}
+		}
+	}
+	if best != nil {
+		return granularResult{best.level, true}
+	}
+	return granularResult{}
+}
+
+func (e *granularEntry) matches(path string) bool {
+	if e.glob != nil {
+		return e.glob.MatchString(path)
+	}
+	if !strings.HasPrefix(path, e.path) {
+		return false
+	}
+	if len(path) == len(e.path) {
+		return true
+	}
+	next := path[len(e.path)]
+	return next == '/' || next == '.'
+}
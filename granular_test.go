@@ -0,0 +1,55 @@
+package timber
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGranularMatching(t *testing.T) {
+	matcher := compileGranulars(map[string]Level{
+		"github.com/us/svc":              INFO,
+		"github.com/us/svc/db/...":       WARNING,
+		"github.com/us/svc/db.connect":   FINEST,
+		"github.com/us/svc/api.*Request": DEBUG,
+		"github.com/us/svc/api.get?ser":  TRACE,
+		"github.com/us/svc/ap":           ERROR,
+		"billing":                        CRITICAL,
+	})
+	tests := []struct {
+		funcPath, name string
+		level          Level
+		ok             bool
+	}{
+		{"github.com/us/svc.main", "", INFO, true},
+		{"github.com/us/svc/cache.Get", "", INFO, true},
+		{"github.com/us/svc/db.Query", "", WARNING, true},
+		{"github.com/us/svc/db/pool.Get", "", WARNING, true},
+		{"github.com/us/svc/db.connect", "", FINEST, true},
+		{"github.com/us/svc/api.handleRequest", "", DEBUG, true},
+		{"github.com/us/svc/api.getUser", "", TRACE, true},
+		{"github.com/us/svc/api.Serve", "", INFO, true},
+		{"github.com/us/svcx.main", "", NONE, false},
+		{"github.com/us/other.main", "", NONE, false},
+		{"github.com/us/svc/db.Query", "billing.invoices", CRITICAL, true},
+		{"github.com/us/svc/db.connect", "billing", FINEST, true},
+		{"github.com/us/other.main", "billingx", NONE, false},
+	}
+	for _, test := range tests {
+		rec := &LogRecord{FuncPath: test.funcPath, Name: test.name}
+		for i := 0; i < 2; i++ { // the second time is cached
+			level, ok := matcher.level(rec)
+			verify(t, test.funcPath+" "+test.name, fmt.Sprint(level, ok), fmt.Sprint(test.level, test.ok))
+		}
+	}
+
+	// per request names don't grow the cache without bound
+	for i := 0; i < 2*maxGranularCache; i++ {
+		rec := &LogRecord{FuncPath: "github.com/us/svc.main", Name: fmt.Sprint("request", i)}
+		if level, ok := matcher.level(rec); level != INFO || !ok {
+			t.Fatalf("got %v %v for request %d, expected INFO", level, ok, i)
+		}
+	}
+	if len(matcher.cache) > maxGranularCache {
+		t.Errorf("cache holds %d results, expected at most %d", len(matcher.cache), maxGranularCache)
+	}
+}
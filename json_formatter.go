@@ -0,0 +1,130 @@
+package timber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JSON formatter writes each record as a single line JSON object:
+//   {"time":"2011-10-20T15:39:07.383485-07:00","level":"INFO","msg":"hi","source":"/path/to/file.go:7",
+//    "func":"pkg.Func","package":"pkg","user":42}
+// "name" is included for records from a Named logger.
+// Fields are added after the standard keys in the order they were logged.  A field
+// with the same key as a standard one is written as "fields.<key>" like "fields.level"
+// so it can't hide the record's own value.
+// The logged error, if any, is added as "error_chain", the types and messages of
+// the error and the errors it wraps:
+//   "error_chain":[{"type":"*fs.PathError","msg":"open x: no such file or directory"},...]
+// A captured stack is added as "stack":
+//   "stack":[{"func":"main.load","file":"/src/main.go","line":12},...]
+// Values that can't be encoded as JSON are written as strings.
+// Defaults:
+// TimeLayout: time.RFC3339Nano
+// ShortLevel: false (full level names like WARNING instead of WARN)
+type JSONFormatter struct {
+	TimeLayout string
+	ShortLevel bool
+}
+
+func NewJSONFormatter() *JSONFormatter {
+	return &JSONFormatter{TimeLayout: time.RFC3339Nano}
+}
+
+// LogFormatter interface
+func (jf *JSONFormatter) Format(rec *LogRecord) string {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	writeJSONString(buf, "time")
+	buf.WriteByte(':')
+	writeJSONString(buf, rec.Timestamp.Format(jf.TimeLayout))
+	jf.writeString(buf, "level", jf.levelString(rec.Level))
+	jf.writeString(buf, "msg", rec.Message)
+	jf.writeString(buf, "source", parseSourceLong(rec.SourceFile, rec.SourceLine))
+	jf.writeString(buf, "func", rec.FuncPath)
+	jf.writeString(buf, "package", rec.PackagePath)
+	if rec.Name != "" {
+		jf.writeString(buf, "name", rec.Name)
+	}
+	for _, field := range rec.Fields {
+		buf.WriteByte(',')
+		key := field.Key
+		if jsonStandardKeys[key] {
+			key = "fields." + key
+		}
+		writeJSONString(buf, key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, field.Value)
+	}
+	if rec.Error != nil {
+		buf.WriteString(`,"error_chain":[`)
+		for i, err := range errorChain(rec.Error) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"type":`)
+			writeJSONString(buf, fmt.Sprintf("%T", err))
+			buf.WriteString(`,"msg":`)
+			writeJSONString(buf, err.Error())
+			buf.WriteByte('}')
+		}
+		buf.WriteByte(']')
+	}
+	if rec.Stack != nil {
+		buf.WriteString(`,"stack":[`)
+		for i, frame := range rec.Stack {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"func":`)
+			writeJSONString(buf, frame.Func)
+			buf.WriteString(`,"file":`)
+			writeJSONString(buf, frame.File)
+			fmt.Fprintf(buf, `,"line":%d}`, frame.Line)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// Keys written by the formatter itself
+var jsonStandardKeys = map[string]bool{
+	"time": true, "level": true, "msg": true, "source": true, "func": true, "package": true,
+	"name": true, "error_chain": true, "stack": true,
+}
+
+func (jf *JSONFormatter) levelString(lvl Level) string {
+	if jf.ShortLevel && lvl >= 0 && int(lvl) < len(LevelStrings) {
+		return LevelStrings[lvl]
+	}
+	return longLevelString(lvl)
+}
+
+func (jf *JSONFormatter) writeString(buf *bytes.Buffer, key, value string) {
+	buf.WriteByte(',')
+	writeJSONString(buf, key)
+	buf.WriteByte(':')
+	writeJSONString(buf, value)
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	// marshalling a string never fails
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	// errors usually have no exported fields so they would encode as {}
+	if err, ok := value.(error); ok {
+		writeJSONString(buf, err.Error())
+		return
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		writeJSONString(buf, fmt.Sprint(value))
+		return
+	}
+	buf.Write(b)
+}
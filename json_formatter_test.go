@@ -0,0 +1,40 @@
+package timber
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJSONFormat(t *testing.T) {
+	rec := *lr
+	rec.Timestamp = time.Date(2011, 10, 20, 22, 39, 7, 383485000, time.UTC)
+	rec.Message = "say \"hi\"\n"
+	rec.Fields = makeFields([]interface{}{"user", 42, "err", errors.New("boom"), "c", complex(1, 2)})
+	jf := NewJSONFormatter()
+	out := `{"time":"2011-10-20T22:39:07.383485Z","level":"INFO","msg":"say \"hi\"\n",` +
+		`"source":"/blah/der/some_file.go:7","func":"hi.Zoot","package":"hi","user":42,"err":"boom","c":"(1+2i)"}` + "\n"
+	verify(t, "json", jf.Format(&rec), out)
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(jf.Format(&rec)), &decoded); err != nil {
+		t.Errorf("invalid json: %v", err)
+	}
+
+	jf.ShortLevel = true
+	jf.TimeLayout = "2006-01-02"
+	rec.Fields = nil
+	rec.Name = "billing"
+	out = `{"time":"2011-10-20","level":"INFO","msg":"say \"hi\"\n",` +
+		`"source":"/blah/der/some_file.go:7","func":"hi.Zoot","package":"hi","name":"billing"}` + "\n"
+	verify(t, "json short", jf.Format(&rec), out)
+
+	// fields can't replace the standard keys
+	rec.Name = ""
+	rec.Fields = makeFields([]interface{}{"level", "debug", "msg", "x", "stack", 1})
+	out = `{"time":"2011-10-20","level":"INFO","msg":"say \"hi\"\n",` +
+		`"source":"/blah/der/some_file.go:7","func":"hi.Zoot","package":"hi",` +
+		`"fields.level":"debug","fields.msg":"x","fields.stack":1}` + "\n"
+	verify(t, "json colliding fields", jf.Format(&rec), out)
+}
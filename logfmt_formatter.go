@@ -0,0 +1,113 @@
+package timber
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Attribute names understood by LogfmtFormatter
+//   ts      - Timestamp formatted with TimeLayout
+//   level   - full level name e.g. INFO
+//   caller  - short source file and line e.g. file.go:12
+//   source  - full source file and line
+//   func    - FuncPath
+//   package - PackagePath
+//   name    - logger name from Named, omitted when empty
+//   msg     - Message
+//   fields  - all the key=value Fields of the record
+//   error   - types and messages of the logged error and its causes, omitted when there's none
+var DefaultLogfmtAttributes = []string{"ts", "level", "caller", "msg", "fields"}
+
+var logfmtAttributeNames = map[string]bool{
+	"ts": true, "level": true, "caller": true, "source": true, "func": true, "package": true,
+	"name": true, "msg": true, "fields": true, "error": true,
+}
+
+// Logfmt formatter writes records as key=value pairs on one line:
+//   ts=2011-10-20T15:39:07.383-07:00 level=INFO caller=file.go:12 msg="hello there" user=42
+// Values containing spaces, quotes, '=' or control characters are quoted and those
+// characters are replaced with '_' in keys.  Fields with the same key as one of the
+// attributes, like level, are written as fields.level so they can't take its place.
+// Defaults:
+// TimeLayout: 2006-01-02T15:04:05.000Z07:00
+// Attributes: DefaultLogfmtAttributes
+type LogfmtFormatter struct {
+	TimeLayout string
+	Attributes []string
+}
+
+func NewLogfmtFormatter() *LogfmtFormatter {
+	return &LogfmtFormatter{"2006-01-02T15:04:05.000Z07:00", DefaultLogfmtAttributes}
+}
+
+// LogFormatter interface
+func (lf *LogfmtFormatter) Format(rec *LogRecord) string {
+	buf := new(bytes.Buffer)
+	for _, attr := range lf.Attributes {
+		switch attr {
+		case "ts":
+			writeLogfmtPair(buf, attr, rec.Timestamp.Format(lf.TimeLayout))
+		case "level":
+			writeLogfmtPair(buf, attr, longLevelString(rec.Level))
+		case "caller":
+			writeLogfmtPair(buf, attr, parseSourceShort(rec.SourceFile, rec.SourceLine))
+		case "source":
+			writeLogfmtPair(buf, attr, parseSourceLong(rec.SourceFile, rec.SourceLine))
+		case "func":
+			writeLogfmtPair(buf, attr, rec.FuncPath)
+		case "package":
+			writeLogfmtPair(buf, attr, rec.PackagePath)
+		case "name":
+			if rec.Name != "" {
+				writeLogfmtPair(buf, attr, rec.Name)
+			}
+		case "msg":
+			writeLogfmtPair(buf, attr, rec.Message)
+		case "error":
+			if rec.Error != nil {
+				writeLogfmtPair(buf, attr, formatErrorChain(rec.Error))
+			}
+		case "fields":
+			for _, field := range rec.Fields {
+				key := fieldKey(field.Key)
+				if lf.isAttribute(key) {
+					key = "fields." + key
+				}
+				writeLogfmtPair(buf, key, fmt.Sprint(field.Value))
+			}
+		}
+	}
+	buf.WriteByte('\n')
+	return buf.String()
+}
+
+// Whether the formatter writes key for one of its attributes
+func (lf *LogfmtFormatter) isAttribute(key string) bool {
+	for _, attr := range lf.Attributes {
+		if attr == key && attr != "fields" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(fieldKey(key))
+	buf.WriteByte('=')
+	buf.WriteString(quoteFieldValue(value))
+}
+
+// Parse a comma separated list of attribute names
+func parseLogfmtAttributes(attributes string) []string {
+	var ret []string
+	for _, attr := range strings.Split(attributes, ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			ret = append(ret, attr)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,33 @@
+package timber
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogfmtFormat(t *testing.T) {
+	rec := *lr
+	rec.Timestamp = time.Date(2011, 10, 20, 22, 39, 7, 383485000, time.UTC)
+	rec.Fields = makeFields([]interface{}{"user", 42, "query", "a=b", "quote", `say "hi"`, "empty", ""})
+	lf := NewLogfmtFormatter()
+	out := `ts=2011-10-20T22:39:07.383Z level=INFO caller=some_file.go:7 msg="hellooooo nurse!" ` +
+		`user=42 query="a=b" quote="say \"hi\"" empty=""` + "\n"
+	verify(t, "logfmt", lf.Format(&rec), out)
+
+	lf.Attributes = parseLogfmtAttributes(" level, func ,package,name,msg")
+	out = `level=INFO func=hi.Zoot package=hi msg="hellooooo nurse!"` + "\n"
+	verify(t, "logfmt attributes", lf.Format(&rec), out)
+
+	// keys can't break the line up and control characters are escaped
+	lf.Attributes = []string{"fields"}
+	rec.Fields = makeFields([]interface{}{"a key", "x", "k=v", "bell\a", "", "tab\there", "esc", "\x1b[31m"})
+	out = `a_key=x k_v="bell\a" _="tab\there" esc="\x1b[31m"` + "\n"
+	verify(t, "logfmt escaping", lf.Format(&rec), out)
+
+	// fields can't pass for the level or message
+	lf.Attributes = DefaultLogfmtAttributes
+	rec.Fields = makeFields([]interface{}{"level", "CRITICAL", "msg", "spoofed", "le vel", 1, "source", "a.go"})
+	out = `ts=2011-10-20T22:39:07.383Z level=INFO caller=some_file.go:7 msg="hellooooo nurse!" ` +
+		`fields.level=CRITICAL fields.msg=spoofed le_vel=1 source=a.go` + "\n"
+	verify(t, "logfmt standard keys", lf.Format(&rec), out)
+}
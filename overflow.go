@@ -0,0 +1,189 @@
+package timber
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// What to do with a record when the buffer between the callers and the writers is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock for a Timber and OverflowDropNewest for ConfigLogger.Async
+	OverflowDefault OverflowPolicy = iota
+	// Wait for room.  A slow writer slows down the callers but nothing is lost
+	OverflowBlock
+	// Drop the record being logged
+	OverflowDropNewest
+	// Drop the oldest record in the buffer to make room
+	OverflowDropOldest
+	// Drop the record being logged if it's below TimberOptions.DropLevel, otherwise
+	// wait for room.  ERROR and above always wait
+	OverflowDropBelowLevel
+)
+
+// Names of the policies in config files
+var overflowPolicyNames = map[string]OverflowPolicy{
+	"block":          OverflowBlock,
+	"dropnewest":     OverflowDropNewest,
+	"dropoldest":     OverflowDropOldest,
+	"dropbelowlevel": OverflowDropBelowLevel,
+}
+
+func parseOverflowPolicy(name string) (OverflowPolicy, error) {
+	policy, ok := overflowPolicyNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return OverflowDefault, fmt.Errorf("unknown overflow policy %q", name)
+	}
+	return policy, nil
+}
+
+// Defaults for TimberOptions
+const (
+	DefaultBufferSize         = 300
+	DefaultDropReportInterval = time.Minute
+)
+
+// Options for NewTimberWithOptions.
+// Defaults:
+//   BufferSize         = DefaultBufferSize
+//   Overflow           = OverflowBlock
+//   DropLevel          = ERROR
+//   DropReportInterval = DefaultDropReportInterval
+type TimberOptions struct {
+	// Number of records that can be waiting for the writers
+	BufferSize int
+	Overflow   OverflowPolicy
+	// Lowest level that isn't dropped with OverflowDropBelowLevel.  Levels above ERROR are
+	// treated as ERROR
+	DropLevel Level
+	// How often a WARNING with the number of records dropped since the last one is logged
+	DropReportInterval time.Duration
+}
+
+func (options TimberOptions) withDefaults() TimberOptions {
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+	if options.Overflow == OverflowDefault {
+		options.Overflow = OverflowBlock
+	}
+	if options.DropLevel == NONE || options.DropLevel > ERROR {
+		options.DropLevel = ERROR
+	}
+	if options.DropReportInterval <= 0 {
+		options.DropReportInterval = DefaultDropReportInterval
+	}
+	return options
+}
+
+// Counts of dropped records, shared by a Timber and its children
+type dropCounter struct {
+	byLevel  [CRITICAL + 1]uint64
+	other    uint64 // levels out of range
+	reported uint64 // total at the last report, only used by the dispatch goroutine
+}
+
+func (dc *dropCounter) add(lvl Level) {
+	if lvl >= NONE && lvl <= CRITICAL {
+		atomic.AddUint64(&dc.byLevel[lvl], 1)
+	} else {
+		atomic.AddUint64(&dc.other, 1)
+	}
+}
+
+func (dc *dropCounter) total() uint64 {
+	total := atomic.LoadUint64(&dc.other)
+	for i := range dc.byLevel {
+		total += atomic.LoadUint64(&dc.byLevel[i])
+	}
+	return total
+}
+
+// A report of the records dropped since the last one, nil if there were none
+func (dc *dropCounter) report(now time.Time) *LogRecord {
+	total := dc.total()
+	if total == dc.reported {
+		return nil
+	}
+	dropped := total - dc.reported
+	dc.reported = total
+	return internalRecord(WARNING, now, []Field{{"dropped", dropped}},
+		"TIMBER! Dropped %d records because the buffer was full", dropped)
+}
+
+// Number of records dropped because the buffer was full
+func (t *Timber) Dropped() uint64 {
+	return t.drops.total()
+}
+
+// Number of records at lvl dropped because the buffer was full
+func (t *Timber) DroppedAt(lvl Level) uint64 {
+	if lvl < NONE || lvl > CRITICAL {
+		return 0
+	}
+	return atomic.LoadUint64(&t.drops.byLevel[lvl])
+}
+
+// Put the record in the buffer following the overflow policy
+func (t *Timber) send(rec *LogRecord) {
+	sendOverflow(t.recordChan, rec, t.overflow, t.dropLevel, t.drops)
+}
+
+// Put the record in records following the policy and count the records dropped
+func sendOverflow(records chan *LogRecord, rec *LogRecord, policy OverflowPolicy, dropLevel Level, drops *dropCounter) {
+	if policy == OverflowBlock {
+		records <- rec
+		return
+	}
+	select {
+	case records <- rec:
+		return
+	default:
+	}
+	switch policy {
+	case OverflowDropNewest:
+		drops.add(rec.Level)
+	case OverflowDropOldest:
+		for {
+			select {
+			case old := <-records:
+				if old != nil {
+					drops.add(old.Level)
+				}
+			default:
+			}
+			select {
+			case records <- rec:
+				return
+			default:
+			}
+		}
+	case OverflowDropBelowLevel:
+		if rec.Level < dropLevel {
+			drops.add(rec.Level)
+			return
+		}
+		records <- rec
+	}
+}
+
+// Source file of the records from timber itself
+const internalSource = "timber"
+
+// A record from timber itself rather than a caller
+func internalRecord(lvl Level, now time.Time, fields []Field, template string, args ...interface{}) *LogRecord {
+	return &LogRecord{
+		Level:       lvl,
+		Timestamp:   now,
+		SourceFile:  internalSource,
+		Message:     fmt.Sprintf(template, args...),
+		Template:    template,
+		FuncPath:    "_",
+		PackagePath: "_",
+		FuncName:    "_",
+		Fields:      fields,
+	}
+}
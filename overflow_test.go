@@ -0,0 +1,95 @@
+package timber
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Blocks the dispatch goroutine in the first write until released
+type gateWriter struct {
+	memWriter
+	once    sync.Once
+	started chan bool
+	gate    chan bool
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan bool), gate: make(chan bool)}
+}
+
+func (w *gateWriter) LogWrite(msg string) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.gate
+	})
+	w.memWriter.LogWrite(msg)
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		expected []string
+		dropped  uint64
+	}{
+		{OverflowDropNewest, []string{"INFO 1", "INFO 2", "INFO 3"}, 3},
+		{OverflowDropOldest, []string{"INFO 1", "INFO 5", "WARN 6"}, 3},
+		{OverflowDropBelowLevel, []string{"INFO 1", "INFO 2", "INFO 3", "WARN 6"}, 2},
+	}
+	for _, test := range tests {
+		log := NewTimberWithOptions(TimberOptions{BufferSize: 2, Overflow: test.overflow, DropLevel: WARNING})
+		writer := newGateWriter()
+		log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%L %M")})
+		log.Info("1")
+		<-writer.started
+		// 2 and 3 fill the buffer
+		for i := 2; i <= 5; i++ {
+			log.Info("%d", i)
+		}
+		done := make(chan bool)
+		if test.overflow == OverflowDropBelowLevel {
+			// waits for room
+			go func() {
+				log.Warn("6")
+				close(done)
+			}()
+		} else {
+			log.Warn("6")
+			close(done)
+		}
+		close(writer.gate)
+		<-done
+		if log.Dropped() != test.dropped || log.DroppedAt(INFO)+log.DroppedAt(WARNING) != test.dropped {
+			t.Errorf("%d: got %d dropped, expected %d", test.overflow, log.Dropped(), test.dropped)
+		}
+		log.Close()
+
+		msgs := writer.msgs
+		if len(msgs) == 0 || !strings.HasPrefix(msgs[len(msgs)-1], "WARN TIMBER! Dropped ") {
+			t.Errorf("%d: missing drop report in %q", test.overflow, msgs)
+			continue
+		}
+		expected := make([]string, len(test.expected))
+		for i, msg := range test.expected {
+			expected[i] = msg + "\n"
+		}
+		checkMsgs(t, msgs[:len(msgs)-1], expected)
+	}
+}
+
+func TestDropReportSource(t *testing.T) {
+	log := NewTimberWithOptions(TimberOptions{BufferSize: 1, Overflow: OverflowDropNewest})
+	writer := newGateWriter()
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%x %M")})
+	log.Info("1")
+	<-writer.started
+	// 2 fills the buffer
+	for i := 2; i <= 4; i++ {
+		log.Info("%d", i)
+	}
+	close(writer.gate)
+	// the drop report is written on Close
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"overflow_test 1\n", "overflow_test 2\n",
+		"timber TIMBER! Dropped 2 records because the buffer was full\n"})
+}
@@ -16,10 +16,76 @@ type PatFormatter struct {
 	formatDynamic []byte
 }
 
-// Split a full package.function into just the package component.
-func splitPackage(pkg string) string {
-	split := strings.Split(pkg, ".")
-	return strings.Join(split[:len(split)-1], "")
+// Split a runtime function name into its package, receiver type and function.
+//   github.com/us/svc.(*Server).Handle       -> github.com/us/svc, *Server, Handle
+//   github.com/us/svc.Server.Handle.func1    -> github.com/us/svc, Server, Handle.func1
+//   github.com/us/svc.Map[...]               -> github.com/us/svc, "", Map
+//   gopkg.in/yaml%2ev2.Unmarshal             -> gopkg.in/yaml.v2, "", Unmarshal
+// Closures keep the name of the function they are in.  Type parameters are dropped.
+func splitFuncPath(funcPath string) (pkg, typ, fn string) {
+	// the package ends at the first '.' after the last '/' outside of type parameters
+	end := len(funcPath)
+	if i := strings.IndexByte(funcPath, '['); i >= 0 {
+		end = i
+	}
+	start := strings.LastIndex(funcPath[:end], "/") + 1
+	dot := strings.IndexByte(funcPath[start:], '.')
+	if dot < 0 {
+		return funcPath, "", ""
+	}
+	pkg = strings.Replace(funcPath[:start+dot], "%2e", ".", -1)
+	rest := stripTypeParams(funcPath[start+dot+1:])
+
+	if strings.HasPrefix(rest, "(") {
+		if i := strings.Index(rest, ")."); i > 0 {
+			return pkg, rest[1:i], rest[i+2:]
+		}
+	}
+	if i := strings.IndexByte(rest, '.'); i > 0 && !isClosureName(rest[i+1:]) {
+		return pkg, rest[:i], rest[i+1:]
+	}
+	return pkg, "", rest
+}
+
+// Just the package of a runtime function name
+func splitPackage(funcPath string) string {
+	pkg, _, _ := splitFuncPath(funcPath)
+	return pkg
+}
+
+// Whether the remainder of a function name after a '.' is a closure like func1,
+// func1.2, gowrap1 or the empty segment of glob..func1
+func isClosureName(name string) bool {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if strings.HasPrefix(name, prefix) {
+			name = name[len(prefix):]
+			break
+		}
+	}
+	return strings.Trim(name, "0123456789") == ""
+}
+
+// Remove [...] from generic function and type names
+func stripTypeParams(name string) string {
+	if !strings.Contains(name, "[") {
+		return name
+	}
+	buf := make([]byte, 0, len(name))
+	depth := 0
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			buf = append(buf, c)
+		}
+	}
+	return string(buf)
 }
 
 // Format codes:
@@ -35,6 +101,12 @@ func splitPackage(pkg string) string {
 //   %% - Percent sign
 // 	 %P - Caller Path: package path + calling function name
 // 	 %p - Caller Path: package path
+//   %R - Receiver: type of the calling method like *Server, empty for functions
+//   %f - Function: calling function name without package or receiver
+//   %F - Fields: key=value pairs of LogRecord.Fields separated by spaces
+//   %N - Name: logger name set with Timber.Named
+//   %E - Error: types and messages of the logged error and the errors it wraps
+//   %K - Stack: one line per function and one for its file and line, each starting with a newline
 // the string number prefixes are allowed e.g.: %10s will pad the source field to 10 spaces
 func NewPatFormatter(format string) *PatFormatter {
 	pf := new(PatFormatter)
@@ -170,6 +242,54 @@ func (pf *PatFormatter) compileForLevel(level int) []byte {
 			sprintfFmt = append(sprintfFmt, 's')
 			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
 			pf.formatDynamic = append(pf.formatDynamic, 'p')
+		case 'F':
+			sprintfFmt = append(sprintfFmt, '%')
+			if num != nil {
+				sprintfFmt = append(sprintfFmt, num...)
+			}
+			sprintfFmt = append(sprintfFmt, 's')
+			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+			pf.formatDynamic = append(pf.formatDynamic, 'F')
+		case 'N':
+			sprintfFmt = append(sprintfFmt, '%')
+			if num != nil {
+				sprintfFmt = append(sprintfFmt, num...)
+			}
+			sprintfFmt = append(sprintfFmt, 's')
+			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+			pf.formatDynamic = append(pf.formatDynamic, 'N')
+		case 'R':
+			sprintfFmt = append(sprintfFmt, '%')
+			if num != nil {
+				sprintfFmt = append(sprintfFmt, num...)
+			}
+			sprintfFmt = append(sprintfFmt, 's')
+			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+			pf.formatDynamic = append(pf.formatDynamic, 'R')
+		case 'f':
+			sprintfFmt = append(sprintfFmt, '%')
+			if num != nil {
+				sprintfFmt = append(sprintfFmt, num...)
+			}
+			sprintfFmt = append(sprintfFmt, 's')
+			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+			pf.formatDynamic = append(pf.formatDynamic, 'f')
+		case 'E':
+			sprintfFmt = append(sprintfFmt, '%')
+			if num != nil {
+				sprintfFmt = append(sprintfFmt, num...)
+			}
+			sprintfFmt = append(sprintfFmt, 's')
+			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+			pf.formatDynamic = append(pf.formatDynamic, 'E')
+		case 'K':
+			sprintfFmt = append(sprintfFmt, '%')
+			if num != nil {
+				sprintfFmt = append(sprintfFmt, num...)
+			}
+			sprintfFmt = append(sprintfFmt, 's')
+			sprintfFmt = append(sprintfFmt, fmt_str[1:]...)
+			pf.formatDynamic = append(pf.formatDynamic, 'K')
 		default:
 			sprintfFmt = append(sprintfFmt, fmt_str...)
 		} // end switch
@@ -214,6 +334,22 @@ func (pf *PatFormatter) getDynamic(rec *LogRecord) []interface{} {
 			ret = append(ret, rec.FuncPath)
 		case 'p':
 			ret = append(ret, rec.PackagePath)
+		case 'F':
+			ret = append(ret, formatFields(rec.Fields))
+		case 'N':
+			ret = append(ret, rec.Name)
+		case 'R':
+			ret = append(ret, rec.ReceiverType)
+		case 'f':
+			ret = append(ret, rec.FuncName)
+		case 'E':
+			if rec.Error != nil {
+				ret = append(ret, formatErrorChain(rec.Error))
+			} else {
+				ret = append(ret, "")
+			}
+		case 'K':
+			ret = append(ret, formatStack(rec.Stack))
 		}
 	}
 	return ret
@@ -229,7 +365,7 @@ func parseSourceShort(file string, line int) string {
 }
 
 func parseSourceXShort(file string) string {
-	return file[strings.LastIndex(file, "/")+1 : (len(file) - 3)]
+	return strings.TrimSuffix(file[strings.LastIndex(file, "/")+1:], ".go")
 }
 
 func parseDate(t time.Time) []interface{} {
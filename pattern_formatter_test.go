@@ -2,6 +2,7 @@ package timber
 
 import (
 	"fmt"
+	stdlog "log"
 	"testing"
 	"time"
 )
@@ -62,6 +63,69 @@ func TestRealPatternFormat(t *testing.T) {
 	verify(t, in, pf.Format(lr), out)
 }
 
+func TestFieldsPatternFormat(t *testing.T) {
+	rec := *lr
+	rec.Fields = makeFields([]interface{}{"user", 42, "path", "/a b", "orphan"})
+	in := "%M %F"
+	out := "hellooooo nurse! user=42 path=\"/a b\" !MISSING=orphan\n"
+	verify(t, in, NewPatFormatter(in).Format(&rec), out)
+	verify(t, in, NewPatFormatter(in).Format(lr), "hellooooo nurse! \n")
+}
+
+var funcPathTests = []struct {
+	in, pkg, typ, fn string
+}{
+	{"main.main", "main", "", "main"},
+	{"github.com/us/svc.Handle", "github.com/us/svc", "", "Handle"},
+	{"github.com/us/svc.(*Server).Handle", "github.com/us/svc", "*Server", "Handle"},
+	{"github.com/us/svc.Server.handle", "github.com/us/svc", "Server", "handle"},
+	{"github.com/us/svc.Handle.func1", "github.com/us/svc", "", "Handle.func1"},
+	{"github.com/us/svc.Handle.func1.2", "github.com/us/svc", "", "Handle.func1.2"},
+	{"github.com/us/svc.(*Server).Handle.func1", "github.com/us/svc", "*Server", "Handle.func1"},
+	{"github.com/us/svc.glob..func1", "github.com/us/svc", "", "glob..func1"},
+	{"github.com/us/svc.Map[...]", "github.com/us/svc", "", "Map"},
+	{"github.com/us/svc.(*List[...]).Push", "github.com/us/svc", "*List", "Push"},
+	{"github.com/us/svc.Map[github.com/us/a.T]", "github.com/us/svc", "", "Map"},
+	{"gopkg.in/yaml%2ev2.(*Decoder).Decode", "gopkg.in/yaml.v2", "*Decoder", "Decode"},
+}
+
+func TestSplitFuncPath(t *testing.T) {
+	for _, tt := range funcPathTests {
+		pkg, typ, fn := splitFuncPath(tt.in)
+		verify(t, tt.in, pkg+" "+typ+" "+fn, tt.pkg+" "+tt.typ+" "+tt.fn)
+	}
+}
+
+type callerType struct{}
+
+func (c *callerType) log(log *Timber) {
+	log.Info("method")
+}
+
+func TestCallerPattern(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer,
+		Level:     DEBUG,
+		Formatter: NewPatFormatter("%p|%R|%f|%M")})
+	new(callerType).log(log)
+	func() {
+		log.Info("closure")
+	}()
+	stdlog.New(log, "", 0).Print("standard log")
+	global := Global
+	Global = log
+	Info("global")
+	Global = global
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{
+		"github.com/ngmoco/timber|*callerType|log|method\n",
+		"github.com/ngmoco/timber||TestCallerPattern.func1|closure\n",
+		"github.com/ngmoco/timber||TestCallerPattern|standard log\n",
+		"github.com/ngmoco/timber||TestCallerPattern|global\n",
+	})
+}
+
 func BenchmarkWorstPatternFormat(b *testing.B) {
 	pf := NewPatFormatter("short:[%d %t] good:[%D %T] levelPadded:[%-10L] long:%S short:%s xs:%10x Msg:%M Fnc:%P Pkg:%p")
 	for i := 0; i < b.N; i++ {
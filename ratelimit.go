@@ -0,0 +1,160 @@
+package timber
+
+import (
+	"time"
+)
+
+// Defaults for RateLimit
+const (
+	DefaultSampleInterval  = time.Second
+	DefaultSummaryInterval = 10 * time.Second
+)
+
+// Limits how many records a ConfigLogger writes so a flood of identical errors
+// doesn't swamp the writer.  Records that pass the level checks and Filter are
+// sampled and then rate limited.  The number of records dropped is reported
+// with a WARNING record written to the same logger every SummaryInterval.
+// Defaults:
+//   Burst           = Rate rounded up
+//   SampleInterval  = DefaultSampleInterval
+//   SummaryInterval = DefaultSummaryInterval
+type RateLimit struct {
+	// Each level has its own token bucket so a flood of INFO doesn't use up the
+	// ERRORs.  It refills at Rate records per second and holds up to Burst.
+	// A Rate of 0 doesn't rate limit
+	Rate  float64
+	Burst int
+	// Of the records from each message template and call site in every SampleInterval,
+	// the first First are written and then every Thereafter-th one.  A Thereafter of 0
+	// drops the rest.  A First of 0 doesn't sample
+	First          int
+	Thereafter     int
+	SampleInterval time.Duration
+	// How often the number of dropped records is written
+	SummaryInterval time.Duration
+}
+
+// The state of a RateLimit for one ConfigLogger, only used by the dispatch goroutine
+type rateLimiter struct {
+	limit       RateLimit
+	buckets     map[Level]*tokenBucket
+	samples     map[sampleKey]int
+	sampleStart time.Time
+	rateLimited int // dropped since the last summary
+	sampled     int
+	lastSummary time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Records with the same key are sampled together
+type sampleKey struct {
+	template string
+	file     string
+	line     int
+}
+
+func newRateLimiter(limit *RateLimit, now time.Time) *rateLimiter {
+	if limit == nil {
+		return nil
+	}
+	rl := &rateLimiter{
+		limit:       *limit,
+		buckets:     make(map[Level]*tokenBucket),
+		samples:     make(map[sampleKey]int),
+		sampleStart: now,
+		lastSummary: now,
+	}
+	if rl.limit.Burst <= 0 {
+		rl.limit.Burst = int(rl.limit.Rate)
+		if float64(rl.limit.Burst) < rl.limit.Rate {
+			rl.limit.Burst++
+		}
+	}
+	if rl.limit.SampleInterval <= 0 {
+		rl.limit.SampleInterval = DefaultSampleInterval
+	}
+	if rl.limit.SummaryInterval <= 0 {
+		rl.limit.SummaryInterval = DefaultSummaryInterval
+	}
+	return rl
+}
+
+// Whether to write the record.  Time is taken from the record so records are
+// limited by when they were logged rather than when they were dispatched
+func (rl *rateLimiter) allow(rec *LogRecord) bool {
+	if rl == nil {
+		return true
+	}
+	if !rl.sample(rec) {
+		rl.sampled++
+		return false
+	}
+	if !rl.take(rec) {
+		rl.rateLimited++
+		return false
+	}
+	return true
+}
+
+func (rl *rateLimiter) sample(rec *LogRecord) bool {
+	if rl.limit.First <= 0 {
+		return true
+	}
+	if rec.Timestamp.Sub(rl.sampleStart) >= rl.limit.SampleInterval {
+		rl.samples = make(map[sampleKey]int)
+		rl.sampleStart = rec.Timestamp
+	}
+	key := sampleKey{rec.Template, rec.SourceFile, rec.SourceLine}
+	if rec.Template == "" {
+		key.template = rec.Message
+	}
+	rl.samples[key]++
+	n := rl.samples[key]
+	if n <= rl.limit.First {
+		return true
+	}
+	return rl.limit.Thereafter > 0 && (n-rl.limit.First)%rl.limit.Thereafter == 0
+}
+
+func (rl *rateLimiter) take(rec *LogRecord) bool {
+	if rl.limit.Rate <= 0 {
+		return true
+	}
+	bucket, ok := rl.buckets[rec.Level]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(rl.limit.Burst), last: rec.Timestamp}
+		rl.buckets[rec.Level] = bucket
+	}
+	if elapsed := rec.Timestamp.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * rl.limit.Rate
+		if bucket.tokens > float64(rl.limit.Burst) {
+			bucket.tokens = float64(rl.limit.Burst)
+		}
+		bucket.last = rec.Timestamp
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// A summary record if records were dropped and it's time to report them or force is set
+func (rl *rateLimiter) summary(now time.Time, force bool) *LogRecord {
+	if rl == nil || rl.rateLimited+rl.sampled == 0 {
+		return nil
+	}
+	elapsed := now.Sub(rl.lastSummary)
+	if !force && elapsed < rl.limit.SummaryInterval {
+		return nil
+	}
+	rec := internalRecord(WARNING, now, []Field{{"rate_limited", rl.rateLimited}, {"sampled", rl.sampled}},
+		"TIMBER! Dropped %d records in the last %v", rl.rateLimited+rl.sampled, elapsed.Truncate(time.Millisecond))
+	rl.rateLimited, rl.sampled = 0, 0
+	rl.lastSummary = now
+	return rec
+}
@@ -0,0 +1,121 @@
+package timber
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Unix(1000, 0)
+	rec := func(lvl Level, line int, offset time.Duration) *LogRecord {
+		return &LogRecord{Level: lvl, Template: "failed %d", SourceFile: "a.go", SourceLine: line,
+			Timestamp: start.Add(offset)}
+	}
+	allowed := func(rl *rateLimiter, recs ...*LogRecord) (n int) {
+		for _, r := range recs {
+			if rl.allow(r) {
+				n++
+			}
+		}
+		return n
+	}
+
+	// first 2 then every 3rd of each call site per second
+	sampler := newRateLimiter(&RateLimit{First: 2, Thereafter: 3}, start)
+	var recs []*LogRecord
+	for i := 0; i < 10; i++ {
+		recs = append(recs, rec(ERROR, 1, 0), rec(ERROR, 2, 0))
+	}
+	if n := allowed(sampler, recs...); n != 2*(2+2) {
+		t.Errorf("sampled: got %d, expected 8", n)
+	}
+	if n := allowed(sampler, rec(ERROR, 1, time.Second)); n != 1 {
+		t.Errorf("next interval: got %d, expected 1", n)
+	}
+
+	// each level gets 2 at once then 1 per half second
+	limiter := newRateLimiter(&RateLimit{Rate: 2}, start)
+	if n := allowed(limiter, rec(ERROR, 1, 0), rec(ERROR, 1, 0), rec(ERROR, 1, 0), rec(INFO, 1, 0)); n != 3 {
+		t.Errorf("burst: got %d, expected 3", n)
+	}
+	if n := allowed(limiter, rec(ERROR, 1, 500*time.Millisecond), rec(ERROR, 1, 500*time.Millisecond)); n != 1 {
+		t.Errorf("refill: got %d, expected 1", n)
+	}
+
+	if limiter.summary(start.Add(time.Second), false) != nil {
+		t.Errorf("summary before the summary interval")
+	}
+	summary := limiter.summary(start.Add(DefaultSummaryInterval), false)
+	if summary == nil {
+		t.Fatalf("missing summary")
+	}
+	verify(t, "summary", summary.Message, "TIMBER! Dropped 2 records in the last 10s")
+	verify(t, "summary fields", formatFields(summary.Fields), "rate_limited=2 sampled=0")
+	if limiter.summary(start.Add(time.Hour), true) != nil {
+		t.Errorf("summary with nothing dropped")
+	}
+}
+
+func TestRateLimitSummarySource(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%x %M"),
+		RateLimit: &RateLimit{Rate: 1}})
+	for i := 0; i < 5; i++ {
+		log.Info("again")
+	}
+	// the summary is written on Close
+	log.Close()
+	if len(writer.msgs) != 2 || writer.msgs[0] != "ratelimit_test again\n" ||
+		!strings.HasPrefix(writer.msgs[1], "timber TIMBER! Dropped 4 records in the last ") {
+		t.Errorf("got %q", writer.msgs)
+	}
+}
+
+func TestConfigRateLimit(t *testing.T) {
+	writer := new(memWriter)
+	RegisterWriterType("limited", func(properties map[string]string) (LogWriter, error) {
+		return writer, nil
+	})
+	config := `<logging>
+  <filter enabled="true">
+    <tag>limited</tag>
+    <type>limited</type>
+    <level>INFO</level>
+    <format name="pattern">%M %F</format>
+    <ratelimit>
+      <first>2</first>
+      <thereafter>5</thereafter>
+      <sampleinterval>1h</sampleinterval>
+    </ratelimit>
+  </filter>
+</logging>`
+	loaded, err := ReadConfig(strings.NewReader(config), "xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := NewTimber()
+	if err := log.LoadConfigModel(loaded); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 12; i++ {
+		log.Error("failed %d", i)
+	}
+	log.Info("different")
+	log.Close()
+	if len(writer.msgs) != 6 {
+		t.Fatalf("got %q, expected 5 records and a summary", writer.msgs)
+	}
+	checkMsgs(t, writer.msgs[:5], []string{"failed 1 \n", "failed 2 \n", "failed 7 \n", "failed 12 \n", "different \n"})
+	if !strings.HasPrefix(writer.msgs[5], "TIMBER! Dropped 8 records") ||
+		!strings.HasSuffix(writer.msgs[5], " rate_limited=0 sampled=8\n") {
+		t.Errorf("summary: got %q", writer.msgs[5])
+	}
+
+	bad := &Config{Filters: []FilterConfig{{Enabled: true, Tag: "bad", Type: "console", Level: "INFO",
+		RateLimit: &RateLimitConfig{Rate: "-1"}}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), `bad rate "-1"`) {
+		t.Errorf("got %v, expected bad rate", err)
+	}
+}
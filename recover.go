@@ -0,0 +1,77 @@
+package timber
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logs a recovered panic at CRITICAL and flushes the writers.  Defer it at the top of
+// a goroutine:
+//   defer log.RecoverAndLog(false)
+// The message is the panic value, the record comes from the function that panicked and
+// carries the stack where it happened for %K and the JSON "stack" array.  With rethrow the panic continues once
+// everything is written, otherwise the deferring function returns normally
+func (t *Timber) RecoverAndLog(rethrow bool) {
+	// recover only works when called by the deferred function itself
+	if r := recover(); r != nil {
+		t.logPanic(r, rethrow)
+	}
+}
+
+// Runs f in a new goroutine that logs a panic with RecoverAndLog instead of crashing
+func (t *Timber) Go(f func()) {
+	go func() {
+		defer t.RecoverAndLog(false)
+		f()
+	}()
+}
+
+func (t *Timber) logPanic(r interface{}, rethrow bool) {
+	if t.IsEnabled(CRITICAL) {
+		stack, skip := panicStack()
+		err, _ := r.(error)
+		msg := fmt.Sprintf("panic: %v", r)
+		// prepare's depth counts itself and prepareAndSendFields before logPanic
+		t.prepareAndSendFields(CRITICAL, "panic: %v", msg, []Field{{"panic", r}}, err, stack, skip+2)
+	}
+	t.Flush()
+	if rethrow {
+		panic(r)
+	}
+}
+
+// The stack of a panicking goroutine from the function that panicked, and the number
+// of frames between the caller of panicStack and that function
+func panicStack() ([]StackFrame, int) {
+	stack := callerStack(2)
+	// skip the deferred calls, runtime.gopanic and the runtime functions that panicked
+	// for the goroutine like runtime.sigpanic
+	skip := 0
+	for i, frame := range stack {
+		if frame.Func == "runtime.gopanic" {
+			skip = i + 1
+			for skip < len(stack)-1 && strings.HasPrefix(stack[skip].Func, "runtime.") {
+				skip++
+			}
+			break
+		}
+	}
+	return stack[skip:], skip
+}
+
+// Logs a recovered panic with Global, see Timber.RecoverAndLog.  Defer it at the top
+// of a goroutine:
+//   defer timber.Recover()
+func Recover() {
+	if r := recover(); r != nil {
+		Global.logPanic(r, false)
+	}
+}
+
+func RecoverAndLog(rethrow bool) {
+	if r := recover(); r != nil {
+		Global.logPanic(r, rethrow)
+	}
+}
+
+func Go(f func()) { Global.Go(f) }
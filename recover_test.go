@@ -0,0 +1,87 @@
+package timber
+
+import (
+	"strings"
+	"testing"
+)
+
+// Signals every write
+type notifyWriter struct {
+	memWriter
+	written chan bool
+}
+
+func (w *notifyWriter) LogWrite(msg string) {
+	w.memWriter.LogWrite(msg)
+	w.written <- true
+}
+
+func panicker(msg string) {
+	panic(msg)
+}
+
+func nilDereference() int {
+	var p *struct{ x int }
+	return p.x
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%L %f %M%K")})
+	noStacks := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: noStacks, Level: DEBUG, Formatter: NewPatFormatter("%L %f %M%K"),
+		StackLevel: StackOff})
+
+	func() {
+		defer log.RecoverAndLog(false)
+		panicker("boom")
+	}()
+	func() {
+		defer log.RecoverAndLog(false)
+		nilDereference()
+	}()
+	var rethrown interface{}
+	func() {
+		defer func() {
+			rethrown = recover()
+		}()
+		defer log.RecoverAndLog(true)
+		panicker("again")
+	}()
+	if rethrown != "again" {
+		t.Errorf("got %v rethrown, expected again", rethrown)
+	}
+
+	// written and flushed before RecoverAndLog returns
+	msgs := writer.msgs
+	if len(msgs) != 3 {
+		t.Fatalf("got %q, expected 3 panics", msgs)
+	}
+	panics := []string{
+		"CRIT panicker panic: boom",
+		"CRIT nilDereference panic: runtime error: invalid memory address or nil pointer dereference",
+		"CRIT panicker panic: again",
+	}
+	for i, expected := range panics {
+		// the stack follows starting at the function that panicked
+		if !strings.HasPrefix(msgs[i], expected+"\n") || !strings.Contains(msgs[i], ".TestRecoverAndLog\n") ||
+			strings.Contains(msgs[i], "runtime.gopanic") {
+			t.Errorf("got %q, expected %q and the stack", msgs[i], expected)
+		}
+	}
+	log.Close()
+	checkMsgs(t, noStacks.msgs, []string{panics[0] + "\n", panics[1] + "\n", panics[2] + "\n"})
+}
+
+func TestGo(t *testing.T) {
+	log := NewTimber()
+	writer := &notifyWriter{written: make(chan bool, 1)}
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%L %M %F")})
+	log.Go(func() {
+		panicker("in goroutine")
+	})
+	<-writer.written
+	checkMsgs(t, writer.msgs, []string{"CRIT panic: in goroutine panic=\"in goroutine\"\n"})
+	log.Close()
+}
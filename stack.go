@@ -0,0 +1,86 @@
+package timber
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// One frame of the stack captured for a LogRecord
+type StackFrame struct {
+	Func string // full function name like github.com/us/svc.(*Server).Handle
+	File string
+	Line int
+}
+
+// Most frames captured for a LogRecord
+const maxStackDepth = 512
+
+// ConfigLogger.StackLevel for a logger that never writes stacks, even with SetStackLevel
+const StackOff = disabledLevel
+
+// Sets the level at and above which records carry the stack of the goroutine that
+// logged them, for every logger without its own ConfigLogger.StackLevel.  NONE, the
+// default, captures no stacks.  Like SetLevel the change is made on the dispatch
+// goroutine after any records already queued have been written, and ignored after Close
+func (t *Timber) SetStackLevel(lvl Level) {
+	tcChan := make(chan int, 1) // buffered
+	select {
+	case <-t.blackHole:
+		return
+	case t.writerConfigChan <- timberConfig{Action: actionStackLevel, Level: lvl, Ret: tcChan}:
+	}
+	<-tcChan
+}
+
+// Whether a record at lvl needs a stack for the Timber or any logger
+func (t *Timber) wantsStack(lvl Level) bool {
+	if stackLevel := Level(atomic.LoadInt32(t.stackLevel)); stackLevel != NONE && lvl >= stackLevel {
+		return true
+	}
+	return lvl >= Level(atomic.LoadInt32(t.loggerStackLevel))
+}
+
+// Whether the logger writes the stack of rec.  StackOff is above every level, and only
+// StackOff drops the stack of a panic.  Only called on the dispatch goroutine
+func (cLog ConfigLogger) wantsStack(rec *LogRecord) bool {
+	if rec.keepStack {
+		return cLog.StackLevel != StackOff
+	}
+	lvl := rec.Level
+	stackLevel := cLog.StackLevel
+	if stackLevel == NONE && cLog.timberStack != nil {
+		stackLevel = *cLog.timberStack
+	}
+	return stackLevel != NONE && lvl >= stackLevel
+}
+
+// The stack of the calling goroutine starting at the caller of Info etc.  depth is
+// the depth prepare passes to runtime.Callers
+func callerStack(depth int) []StackFrame {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(depth+1, pcs)
+	for n == len(pcs) && len(pcs) < maxStackDepth {
+		pcs = make([]uintptr, 2*len(pcs))
+		n = runtime.Callers(depth+1, pcs)
+	}
+	stack := make([]StackFrame, 0, n)
+	frames := runtime.CallersFrames(pcs[:n])
+	for more := n > 0; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		stack = append(stack, StackFrame{Func: frame.Function, File: frame.File, Line: frame.Line})
+	}
+	return stack
+}
+
+// Render a stack like a panic does, each function on a new line followed by its
+// indented file and line
+func formatStack(stack []StackFrame) string {
+	buf := new(bytes.Buffer)
+	for _, frame := range stack {
+		fmt.Fprintf(buf, "\n%s\n\t%s:%d", frame.Func, frame.File, frame.Line)
+	}
+	return buf.String()
+}
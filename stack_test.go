@@ -0,0 +1,71 @@
+package timber
+
+import (
+	"strings"
+	"testing"
+)
+
+func logFromHelper(log *Timber, lvl Level, msg string) {
+	log.Log(lvl, msg)
+}
+
+func TestStackLevel(t *testing.T) {
+	log := NewTimber()
+	withStacks := new(memWriter)
+	other := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: withStacks, Level: DEBUG, Formatter: NewPatFormatter("%L %M%K"),
+		StackLevel: ERROR})
+	log.AddLogger(ConfigLogger{LogWriter: other, Level: DEBUG, Formatter: NewPatFormatter("%L %M%K")})
+	noStacks := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: noStacks, Level: DEBUG, Formatter: NewPatFormatter("%L %M%K"),
+		StackLevel: filterStackLevel(FilterConfig{StackLevel: "off"})})
+	logFromHelper(log, WARNING, "slow")
+	logFromHelper(log, ERROR, "failed")
+	log.SetStackLevel(WARNING)
+	logFromHelper(log, WARNING, "slow")
+	log.Close()
+	log.SetStackLevel(ERROR) // ignored after Close
+
+	checkMsgs(t, noStacks.msgs, []string{"WARN slow\n", "EROR failed\n", "WARN slow\n"})
+
+	if len(withStacks.msgs) != 3 || len(other.msgs) != 3 {
+		t.Fatalf("got %q and %q", withStacks.msgs, other.msgs)
+	}
+	checkMsgs(t, []string{withStacks.msgs[0], withStacks.msgs[2], other.msgs[0], other.msgs[1]},
+		[]string{"WARN slow\n", "WARN slow\n", "WARN slow\n", "EROR failed\n"})
+	for _, msg := range []string{withStacks.msgs[1], other.msgs[2]} {
+		lines := strings.Split(msg, "\n")
+		// the stack starts at the caller of Log followed by its caller
+		if len(lines) < 6 || !strings.HasSuffix(lines[1], ".logFromHelper") || !strings.HasSuffix(lines[3], ".TestStackLevel") ||
+			!strings.Contains(lines[2], "stack_test.go:") {
+			t.Errorf("bad stack in %q", msg)
+		}
+	}
+}
+
+func TestStackFormat(t *testing.T) {
+	rec := *lr
+	rec.Stack = []StackFrame{{"main.load", "/src/main.go", 12}, {"main.main", "/src/main.go", 5}}
+	pf := NewPatFormatter("%M%K")
+	verify(t, "stack pattern", pf.Format(&rec), rec.Message+"\nmain.load\n\t/src/main.go:12\nmain.main\n\t/src/main.go:5\n")
+
+	jf := &JSONFormatter{TimeLayout: "2006"}
+	out := `{"time":"` + rec.Timestamp.Format("2006") + `","level":"INFO","msg":"` + rec.Message + `",` +
+		`"source":"/blah/der/some_file.go:7","func":"hi.Zoot","package":"hi","stack":[` +
+		`{"func":"main.load","file":"/src/main.go","line":12},{"func":"main.main","file":"/src/main.go","line":5}]}` + "\n"
+	verify(t, "stack json", jf.Format(&rec), out)
+
+	config := `<logging><filter enabled="true"><tag>x</tag><type>console</type><level>INFO</level>
+		<stacklevel>error</stacklevel></filter></logging>`
+	loaded, err := ReadConfig(strings.NewReader(config), "xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := filterStackLevel(loaded.Filters[0]); level != ERROR {
+		t.Errorf("got stack level %v, expected ERROR", level)
+	}
+	loaded.Filters[0].StackLevel = "LOUD"
+	if err := loaded.Validate(); err == nil || !strings.Contains(err.Error(), `unknown level "LOUD"`) {
+		t.Errorf("got %v, expected unknown level", err)
+	}
+}
@@ -1,9 +1,11 @@
 package timber
 
 import (
+	"bytes"
 	"fmt"
 	"log/syslog"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -23,12 +25,90 @@ var DefaultSeverityMap = map[Level]syslog.Priority{
 	CRITICAL: syslog.LOG_CRIT,
 }
 
+// Facility names as used by syslog.conf
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// Severity names as used by syslog.conf
+var syslogSeverities = map[string]syslog.Priority{
+	"emerg":   syslog.LOG_EMERG,
+	"alert":   syslog.LOG_ALERT,
+	"crit":    syslog.LOG_CRIT,
+	"err":     syslog.LOG_ERR,
+	"warning": syslog.LOG_WARNING,
+	"notice":  syslog.LOG_NOTICE,
+	"info":    syslog.LOG_INFO,
+	"debug":   syslog.LOG_DEBUG,
+}
+
+// Get the syslog facility for a name like local3
+func ParseSyslogFacility(name string) (syslog.Priority, error) {
+	facility, ok := syslogFacilities[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("TIMBER! Unknown syslog facility: %s", name)
+	}
+	return facility, nil
+}
+
+// Parse a comma separated list of LEVEL=severity pairs, e.g. "DEBUG=info,WARNING=notice",
+// into a copy of DefaultSeverityMap with those levels overridden
+func ParseSeverityMap(mapping string) (map[Level]syslog.Priority, error) {
+	severityMap := make(map[Level]syslog.Priority, len(DefaultSeverityMap))
+	for lvl, severity := range DefaultSeverityMap {
+		severityMap[lvl] = severity
+	}
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("TIMBER! Bad severity mapping, expected LEVEL=severity: %s", pair)
+		}
+		lvl, err := parseLevel(parts[0])
+		if err != nil || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("TIMBER! Unknown level in severity mapping: %s", parts[0])
+		}
+		severity, ok := syslogSeverities[strings.ToLower(strings.TrimSpace(parts[1]))]
+		if !ok {
+			return nil, fmt.Errorf("TIMBER! Unknown syslog severity: %s", parts[1])
+		}
+		severityMap[lvl] = severity
+	}
+	return severityMap, nil
+}
+
 // Syslog formatter wraps a PatFormatter but adds the 
 // syslog protocol format to the message.  
 // Defaults:
 // Facility: syslog.LOG_USER (1 << 3 for pre-go1.1 compatibility)
 // Hostname: os.Hostname()
 // Tag: os.Args[0]
+// RFC5424: false (legacy BSD RFC 3164 header)
+//
+// In RFC 5424 mode the Tag is the APP-NAME, the pid is the PROCID and
+// the record Fields are written as STRUCTURED-DATA with the StructuredDataID:
+//   <14>1 2011-10-20T15:39:07.383485-07:00 host app 123 - [timber@32473 user="42"] msg
 type SyslogFormatter struct {
 	pf          *PatFormatter
 	pid         int
@@ -36,15 +116,42 @@ type SyslogFormatter struct {
 	Tag         string
 	Facility    syslog.Priority
 	SeverityMap map[Level]syslog.Priority
+	RFC5424     bool
+	// RFC 5424 only. MSGID is "-" when empty
+	MsgID string
+	// RFC 5424 only. SD-ID for the fields element, it should be name@<your private enterprise number>
+	StructuredDataID string
 }
 
+// The SD-ID used for fields in RFC 5424 mode. 32473 is the enterprise number reserved
+// for documentation so you should set your own StructuredDataID
+const DefaultStructuredDataID = "timber@32473"
+
 func NewSyslogFormatter(format string) *SyslogFormatter {
 	hostname, _ := os.Hostname()
-	return &SyslogFormatter{NewPatFormatter(format), os.Getpid(), hostname, os.Args[0], syslog.Priority(1 << 3), DefaultSeverityMap}
+	return &SyslogFormatter{
+		pf:               NewPatFormatter(format),
+		pid:              os.Getpid(),
+		Hostname:         hostname,
+		Tag:              os.Args[0],
+		Facility:         syslog.Priority(1 << 3),
+		SeverityMap:      DefaultSeverityMap,
+		StructuredDataID: DefaultStructuredDataID,
+	}
+}
+
+// Same as NewSyslogFormatter but produces RFC 5424 messages
+func NewRFC5424SyslogFormatter(format string) *SyslogFormatter {
+	sf := NewSyslogFormatter(format)
+	sf.RFC5424 = true
+	return sf
 }
 
 func (sf *SyslogFormatter) Format(rec *LogRecord) string {
 	msg := sf.pf.Format(rec)
+	if sf.RFC5424 {
+		return sf.format5424(rec, msg)
+	}
 	return fmt.Sprintf("<%d>%.15s %s[%d]: %s",
 		sf.Facility|sf.SeverityMap[rec.Level],
 		rec.Timestamp.Format(time.Stamp),
@@ -53,3 +160,67 @@ func (sf *SyslogFormatter) Format(rec *LogRecord) string {
 		msg)
 }
 
+// RFC 5424 allows at most microsecond precision
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+func (sf *SyslogFormatter) format5424(rec *LogRecord, msg string) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		sf.Facility|sf.SeverityMap[rec.Level],
+		rec.Timestamp.Format(rfc5424Time),
+		syslogHeaderField(sf.Hostname, 255),
+		syslogHeaderField(sf.Tag, 48),
+		sf.pid,
+		syslogHeaderField(sf.MsgID, 32),
+		sf.structuredData(rec.Fields),
+		msg)
+}
+
+func (sf *SyslogFormatter) structuredData(fields []Field) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteByte('[')
+	buf.WriteString(syslogSDName(sf.StructuredDataID))
+	for _, field := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(syslogSDName(field.Key))
+		buf.WriteString(`="`)
+		sdValueEscaper.WriteString(buf, fmt.Sprint(field.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// PARAM-VALUE must escape '"', '\' and ']'
+var sdValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// Header fields are printable ASCII without spaces, "-" when empty
+func syslogHeaderField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// SD-NAMEs are header fields that also exclude '=', ']' and '"' and are at most 32 characters
+func syslogSDName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	return syslogHeaderField(name, 32)
+}
+
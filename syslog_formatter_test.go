@@ -0,0 +1,55 @@
+package timber
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRFC5424SyslogFormat(t *testing.T) {
+	rec := *lr
+	rec.Timestamp = time.Date(2011, 10, 20, 22, 39, 7, 383485123, time.UTC)
+	sf := NewRFC5424SyslogFormatter("%M")
+	sf.pid = 123
+	sf.Hostname = "host"
+	sf.Tag = "my app"
+	out := "<14>1 2011-10-20T22:39:07.383485Z host myapp 123 - - hellooooo nurse!\n"
+	verify(t, "rfc5424", sf.Format(&rec), out)
+
+	sf.MsgID = "REQ"
+	sf.Hostname = ""
+	rec.Level = ERROR
+	rec.Fields = makeFields([]interface{}{"user", 42, "a b=", `q"\]`})
+	out = `<11>1 2011-10-20T22:39:07.383485Z - myapp 123 REQ [timber@32473 user="42" ab_="q\"\\\]"] hellooooo nurse!` + "\n"
+	verify(t, "rfc5424 sd", sf.Format(&rec), out)
+}
+
+func TestSyslogFormatterConfig(t *testing.T) {
+	formatter, err := newConfigSyslogFormatter(map[string]string{
+		"facility":   "local3",
+		"tag":        "app",
+		"hostname":   "host",
+		"severities": "INFO=notice, DEBUG=info",
+	})
+	if err != nil {
+		t.Fatalf("getFormatter: %v", err)
+	}
+	sf := formatter.(*SyslogFormatter)
+	sf.pid = 123
+	rec := *lr
+	rec.Timestamp = time.Date(2011, 10, 20, 22, 39, 7, 0, time.UTC)
+	verify(t, "syslog config", sf.Format(&rec), "<157>Oct 20 22:39:07 app[123]: hellooooo nurse!\n")
+	if DefaultSeverityMap[INFO] != 6 {
+		t.Errorf("DefaultSeverityMap was modified")
+	}
+
+	for _, props := range []map[string]string{
+		{"facility": "local9"},
+		{"severities": "INFO"},
+		{"severities": "LOUD=info"},
+		{"severities": "INFO=loud"},
+	} {
+		if _, err := newConfigSyslogFormatter(props); err == nil {
+			t.Errorf("expected error for %v", props)
+		}
+	}
+}
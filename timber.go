@@ -2,7 +2,7 @@
 // multiple output destinations with configurable formats and levels
 // for each.  It also supports granular output configuration to get
 // more detailed logging for specific files/packages. Timber includes
-// support for standard XML or JSON config files to get you started
+// support for standard XML, JSON, YAML or TOML config files to get you started
 // quickly.  It's also easy to configure in code if you want to DIY.
 //
 // Basic use:
@@ -62,7 +62,17 @@
 //		    <format name="pattern">%L %M</property>
 //		  </filter>
 //		</logging>
-// The <tag> is ignored.
+// The <tag> names the filter in config errors and must be unique.
+//
+// Config files are checked before anything is added and all of the problems are
+// returned together as ConfigErrors.  If any filter is invalid no loggers are added.
+// Use WatchConfig instead of LoadConfig to reload the file when it changes or on SIGHUP.
+// YAML and TOML config files have the same structure as the JSON config (see the example
+// timber.yaml and timber.toml) and LoadConfigReader loads a config from any io.Reader.
+//
+// Config values may use environment variables as ${VAR} or ${VAR:-default}, and after
+// parsing TIMBER_LEVEL, TIMBER_FILTER_<TAG>_LEVEL, TIMBER_FILTER_<TAG>_ENABLED and
+// TIMBER_FILTER_<TAG>_<PROPERTY> override the level, enabled flag or a property.
 //
 // To configure the pattern formatter all filters accept:
 //		<format name="pattern">[%D %T] %L %M</format>
@@ -79,14 +89,98 @@
 // 		%% - Percent sign
 // 		%P - Caller Path: packagePath.CallingFunctionName
 // 		%p - Caller Path: packagePath
+// 		%R - Receiver: type of the calling method like *Server, empty for functions
+// 		%f - Function: calling function name without package or receiver
+// 		%F - Fields: key=value pairs from the structured logging methods (Infow etc)
+// 		%N - Name: logger name set with Named
+// 		%E - Error: types and messages of the logged error and the errors it wraps
+// 		%K - Stack: the stack of the goroutine that logged the record, on the following lines
 // the string number prefixes are allowed e.g.: %10s will pad the source field to 10 spaces
 // pattern defaults to %M
 // Both log4go synatax of <property name="format"> and new <format name=type> are supported
 // the property syntax will only ever support the pattern formatter
+//
+// To write one JSON object per line use:
+//		<format name="json"></format>
+// with optional properties <property name="timeformat"> for a go time layout (default RFC3339Nano)
+// and <property name="levelformat">short</property> for the 4 character level names
+//
+// To write logfmt key=value lines use:
+//		<format name="logfmt"></format>
+// with optional properties <property name="timeformat"> and <property name="attributes">
+// listing the record attributes to include e.g. ts,level,caller,msg,fields
+// (also: source, func, package, name, error)
+//
+// To add a syslog header to the pattern use:
+//		<format name="syslog">%M</format>
+// The legacy BSD (RFC 3164) header is used by default.  <property name="rfc">5424</property>
+// selects RFC 5424 with optional <property name="msgid"> and <property name="sdid">
+// (the SD-ID for the structured data built from the record fields)
+// The syslog header may be configured with <property name="facility">local3</property>,
+// <property name="tag">, <property name="hostname"> and a severity map of level names
+// to syslog severities like <property name="severities">DEBUG=info,WARNING=notice</property>
+// Custom writers and formatters may be used from config files by registering them before
+// loading the config with RegisterWriterType and RegisterFormatterType.  The factories are
+// given the filter's properties by name.  The built-in types are registered the same way.
+// RegisterValidatedWriterType also checks the properties when the config is validated,
+// before any writer is created.
+//
 // To configure granulars:
 //   - Create one or many <granular> within a filter
 //   - Define a <level> and <path> within, where path can be path to package or path to
 //     package.FunctionName. Function name definitions override package paths.
+//   - A path may also be a logger name given to Named, which overrides package paths
+//   - A path also covers everything below it, so github.com/us/svc covers github.com/us/svc/db
+//     and github.com/us/svc.Handler.  github.com/us/svc/... means the same thing.
+//     Of several matching paths the longest wins
+//   - A path may be a glob where * matches anything and ? one character like *.handleRequest
+//
+// To only write some of the records that pass the level checks:
+//   - Create one or many <include> and <exclude> within a filter
+//   - Within each, <message> is a regular expression for the message, <source> is a
+//     source file pattern like payments/*.go and <field name="key">value</field> matches
+//     a field from the structured logging methods.  All of them must match.
+//   - Records are written if they match any <include> (or there are none) and no <exclude>
+//   - In code, set ConfigLogger.Filter to a LogFilter like MessageFilter or your own LogFilterFunc
+//
+// To keep a flood of identical messages from swamping a writer add a <ratelimit> to the filter
+// with any of <rate>, <burst>, <first>, <thereafter>, <sampleinterval> and <summaryinterval>.
+// Each level gets a token bucket of rate records per second, and of the records from each
+// message and call site only the first few in every sample interval then every thereafter-th
+// one are written.  A WARNING with the number of records dropped is written every summary
+// interval.  In code set ConfigLogger.RateLimit
+//
+// To collapse repeated messages like syslogd add <dedup>30s</dedup> to the filter.  Consecutive
+// records with the same level, source line and message are written once and followed by
+// "last message repeated N times" when a different record arrives or the 30s are up.
+// In code set ConfigLogger.DedupWindow
+//
+// Records wait in a buffer for the writers.  By default a full buffer makes the callers wait,
+// NewTimberWithOptions sets the buffer size and an OverflowPolicy to drop the newest or oldest
+// records, or only those below a level, instead.  Dropped and DroppedAt count the dropped
+// records and a WARNING with the count is written every TimberOptions.DropReportInterval
+//
+// All of the writers are called from one goroutine so one that blocks, like a socket to a
+// hung server, holds up the rest.  Add <async> to its filter with any of <buffersize>,
+// <overflow> (dropnewest by default, block, dropoldest or dropbelowlevel), <droplevel> and
+// <dropreportinterval> to give it a buffer and goroutine of its own.  In code set
+// ConfigLogger.Async.  Close still writes everything that was queued
+//
+// To debug errors without reproducing them, records at or above a stack level carry the stack
+// of the goroutine that logged them for the %K pattern verb and the JSON "stack" array.  Set it
+// for every logger with SetStackLevel or for one with <stacklevel>ERROR</stacklevel> in its
+// filter or ConfigLogger.StackLevel.  <stacklevel>off</stacklevel> or StackOff keeps a
+// logger's records free of stacks
+//
+// Defer RecoverAndLog(rethrow) or timber.Recover() at the top of a goroutine, or start it with
+// Go(f), to log a panic and its stack at CRITICAL.  Everything logged is written and flushed
+// before the panic continues or the goroutine returns:
+//   defer log.RecoverAndLog(false)
+//
+// Records below the level of every logger and granular return before the caller or message
+// is looked up.  Use IsEnabled(level) to skip building expensive arguments as well, or pass a
+// func() string instead of the format string to build the message only if it will be written:
+//   log.Finest(func() string { return "state: " + dumpState() })
 //
 // Code Architecture:
 // A MultiLogger <logging> which consists of many ConfigLoggers <filter>. ConfigLoggers have three properties:
@@ -97,7 +191,9 @@
 // before writing.  Because the LogFormatters and LogWriters are simple interfaces, it is easy to
 // write your own custom implementations.
 //
-// Once configured, you only deal with the "Logger" interface and use the log methods in your code
+// Once configured, you only deal with the "Logger" interface and use the log methods in your code.
+// The "FieldLogger" interface adds methods like Infow(msg, "key", value) that attach key/value
+// Fields to the LogRecord, which the pattern formatter renders with %F
 //
 // The motivation for this package grew from a need to make some changes to the functionality of
 // log4go (which had already been integrated into a larger project).  I tried to maintain compatiblity
@@ -109,11 +205,13 @@ package timber
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -132,7 +230,9 @@ const (
 	CRITICAL
 )
 
-// Default level passed to runtime.Caller by Timber, add to this if you wrap Timber in your own logging code
+// Default level passed to runtime.Caller by Timber, add to this if you wrap Timber in your own logging code.
+// It finds the caller of the Timber methods and of the package-level functions alike so a wrapper
+// adds the number of its own frames either way
 const DefaultFileDepth int = 3
 
 // What gets printed for each Log level
@@ -151,14 +251,27 @@ var LongLevelStrings = []string{
 	"CRITICAL",
 }
 
-// Return a given level string as the actual Level value
-func getLevel(lvlString string) Level {
+// Return a given level string as the actual Level value.  An empty
+// string is NONE and unknown names are an error
+func parseLevel(lvlString string) (Level, error) {
+	lvlString = strings.ToUpper(strings.TrimSpace(lvlString))
+	if lvlString == "" {
+		return NONE, nil
+	}
 	for idx, str := range LongLevelStrings {
 		if str == lvlString {
-			return Level(idx)
+			return Level(idx), nil
 		}
 	}
-	return Level(0)
+	return NONE, fmt.Errorf("unknown level %q", lvlString)
+}
+
+// Full level name or the number for levels out of range
+func longLevelString(lvl Level) string {
+	if lvl < 0 || int(lvl) >= len(LongLevelStrings) {
+		return strconv.Itoa(int(lvl))
+	}
+	return LongLevelStrings[lvl]
 }
 
 // This explicitly defines the contract for a logger
@@ -209,13 +322,24 @@ type LogWriter interface {
 // This packs up all the message data and metadata. This structure
 // will be passed to the LogFormatter
 type LogRecord struct {
-	Level       Level
-	Timestamp   time.Time
-	SourceFile  string
-	SourceLine  int
-	Message     string
-	FuncPath    string
-	PackagePath string
+	Level        Level
+	Timestamp    time.Time
+	SourceFile   string
+	SourceLine   int
+	Message      string
+	Template     string // Message before any arguments were formatted into it, may be empty
+	FuncPath     string
+	PackagePath  string
+	ReceiverType string  // type of the calling method like *Server, empty for functions
+	FuncName     string  // calling function without package or receiver
+	Fields       []Field // optional key/value pairs in the order they were logged
+	Name         string  // name of the logger set with Named, empty for unnamed loggers
+	Error        error   // the error that was logged, if any
+	// stack of the goroutine that logged the record starting at the caller, only
+	// captured at or above the stack level (see SetStackLevel) or for a recovered panic
+	Stack []StackFrame
+	// the stack was logged with the record like a panic's rather than for the stack level
+	keepStack bool
 }
 
 // Format a log message before writing
@@ -230,15 +354,41 @@ type ConfigLogger struct {
 	Level     Level
 	Formatter LogFormatter
 	Granulars map[string]Level
+	// Optional, records that pass the level checks are only written if the filter allows them
+	Filter LogFilter
+	// Optional, limits how many records are written
+	RateLimit *RateLimit
+	// Optional, consecutive records with the same level, source line and message within
+	// this long of the first are written once followed by "last message repeated N times"
+	DedupWindow time.Duration
+	// Optional, formats and writes the records on the logger's own goroutine so a slow
+	// LogWriter doesn't hold up the other loggers.  Changing the logger waits for the
+	// records it has queued to be written
+	Async *AsyncOptions
+	// Optional, records at or above this level are written with the stack of the goroutine
+	// that logged them.  NONE uses the level set with Timber.SetStackLevel and StackOff
+	// writes none
+	StackLevel Level
+	// state of the dispatch goroutine
+	granulars *granularMatcher
+	limiter   *rateLimiter
+	dedup     *deduper
+	queue     *writerQueue
+	// the stack level set with Timber.SetStackLevel
+	timberStack *Level
 }
 
 // Allow logging to multiple places
 type MultiLogger interface {
-	// returns an int that identifies the logger for future calls to SetLevel and SetFormatter
+	// returns an int that identifies the logger for future calls to SetLevel and SetFormatter,
+	// -1 once closed
 	AddLogger(logger ConfigLogger) int
-	// dynamically change level or format
-	SetLevel(index int, lvl Level)
-	SetFormatter(index int, formatter LogFormatter)
+	// dynamically change level or format, returns an error for an unknown index or once closed
+	SetLevel(index int, lvl Level) error
+	SetFormatter(index int, formatter LogFormatter) error
+	// detach or swap a logger, closing its LogWriter once pending records are written
+	RemoveLogger(index int) error
+	ReplaceLogger(index int, logger ConfigLogger) error
 	Close()
 }
 
@@ -257,7 +407,8 @@ type MultiLogger interface {
 // NOTE: I don't supporting the log4go special handling of the first parameter based on type
 // mainly cuz I don't think it's particularly useful (I kept passing a data string as the first
 // param and expecting a Println-like output but that would always break expecting a format string)
-// I also don't support the passing of the closure stuff
+// A string is always a format string.  A func() string closure builds the message only if it
+// will be written, and anything else like an error is printed with fmt.Sprint rather than panicking
 type Timber struct {
 	writerConfigChan chan timberConfig
 	recordChan       chan *LogRecord
@@ -265,8 +416,21 @@ type Timber struct {
 	closeLatch       *sync.Once
 	blackHole        chan int
 	// This value is passed to runtime.Caller to get the file name/line and may require
-	// tweaking if you want to wrap the logger
+	// tweaking if you want to wrap the logger: DefaultFileDepth plus the depth of the wrapper
 	FileDepth int
+	// bound by With and Named, added to every record
+	fields []Field
+	name   string
+	// see TimberOptions
+	overflow           OverflowPolicy
+	dropLevel          Level
+	dropReportInterval time.Duration
+	drops              *dropCounter
+	// lowest level any logger writes, see IsEnabled
+	minLevel *int32
+	// see SetStackLevel, and the lowest ConfigLogger.StackLevel
+	stackLevel       *int32
+	loggerStackLevel *int32
 }
 
 type timberAction int
@@ -274,43 +438,120 @@ type timberAction int
 const (
 	actionAdd timberAction = iota
 	actionModify
+	actionStackLevel
+	actionFlush
 	actionQuit
 )
 
 type timberConfig struct {
-	Action timberAction // type of config action
-	Index  int          // only for modify
-	Cfg    ConfigLogger // used for modify or add
-	Ret    chan int     // only used for add
+	Action timberAction        // type of config action
+	Index  int                 // only for modify
+	Cfg    ConfigLogger        // only used for add
+	Modify func(*ConfigLogger) // only used for modify, applied on the dispatch goroutine
+	Level  Level               // only used for stack level
+	Ret    chan int            // index for add, -1 on modify of an unknown index
 }
 
 // Creates a new Timber logger that is ready to be configured
 // With no subsequent configuration, nothing will be logged
 //
 func NewTimber() *Timber {
+	return NewTimberWithOptions(TimberOptions{})
+}
+
+// Creates a new Timber logger with a different buffer size or overflow policy,
+// see TimberOptions for the defaults
+func NewTimberWithOptions(options TimberOptions) *Timber {
+	options = options.withDefaults()
 	t := new(Timber)
 	t.writerConfigChan = make(chan timberConfig)
-	t.recordChan = make(chan *LogRecord, 300)
+	t.recordChan = make(chan *LogRecord, options.BufferSize)
 	t.FileDepth = DefaultFileDepth
 	t.closeLatch = &sync.Once{}
 	t.blackHole = make(chan int)
+	t.overflow = options.Overflow
+	t.dropLevel = options.DropLevel
+	t.dropReportInterval = options.DropReportInterval
+	t.drops = new(dropCounter)
+	t.minLevel = new(int32)
+	*t.minLevel = int32(disabledLevel)
+	t.stackLevel = new(int32)
+	t.loggerStackLevel = new(int32)
+	*t.loggerStackLevel = int32(disabledLevel)
 	go t.asyncLumberJack()
 	return t
 }
 
+// How often the dispatch goroutine checks for held back records to write
+const flushCheckInterval = time.Second
+
 func (t *Timber) asyncLumberJack() {
 	var loggers []ConfigLogger = make([]ConfigLogger, 0, 2)
+	// the stack level set with SetStackLevel for the records being dispatched
+	var stackLevel Level
+	// only tick once a logger holds back records
+	var flushTicker *time.Ticker
+	var flushTick <-chan time.Time
+	startFlushes := func(cLog ConfigLogger) {
+		if (cLog.limiter != nil || cLog.dedup != nil) && flushTicker == nil {
+			flushTicker = time.NewTicker(flushCheckInterval)
+			flushTick = flushTicker.C
+		}
+	}
+	// only report drops if records can be dropped
+	var dropTick <-chan time.Time
+	if t.overflow != OverflowBlock {
+		dropTicker := time.NewTicker(t.dropReportInterval)
+		defer dropTicker.Stop()
+		dropTick = dropTicker.C
+	}
 	loopIt := true
 	for loopIt {
 		select {
 		case rec := <-t.recordChan:
 			sendToLoggers(loggers, rec)
+		case now := <-dropTick:
+			if rec := t.drops.report(now); rec != nil {
+				sendToLoggers(loggers, rec)
+			}
+		case now := <-flushTick:
+			for _, cLog := range loggers {
+				flushLogger(cLog, now, false)
+			}
 		case cfg := <-t.writerConfigChan:
 			switch cfg.Action {
 			case actionAdd:
+				cfg.Cfg.compile(&stackLevel)
+				startFlushes(cfg.Cfg)
 				loggers = append(loggers, cfg.Cfg)
+				t.setMinLevel(loggers)
 				cfg.Ret <- (len(loggers) - 1)
 			case actionModify:
+				if cfg.Index < 0 || cfg.Index >= len(loggers) || loggers[cfg.Index].LogWriter == nil {
+					cfg.Ret <- -1
+					continue
+				}
+				// records sent before the modify was requested are written with the old config
+				drainRecords(t.recordChan, loggers)
+				flushLogger(loggers[cfg.Index], time.Now(), true)
+				loggers[cfg.Index].queue.stop()
+				cfg.Modify(&loggers[cfg.Index])
+				loggers[cfg.Index].compile(&stackLevel)
+				startFlushes(loggers[cfg.Index])
+				t.setMinLevel(loggers)
+				cfg.Ret <- cfg.Index
+			case actionStackLevel:
+				// records sent before the change keep their stacks
+				drainRecords(t.recordChan, loggers)
+				stackLevel = cfg.Level
+				atomic.StoreInt32(t.stackLevel, int32(cfg.Level))
+				cfg.Ret <- 0
+			case actionFlush:
+				drainRecords(t.recordChan, loggers)
+				for _, cLog := range loggers {
+					cLog.flush()
+				}
+				cfg.Ret <- 0
 			case actionQuit:
 				close(t.blackHole)
 				close(t.recordChan)
@@ -325,11 +566,142 @@ func (t *Timber) asyncLumberJack() {
 	for rec := range t.recordChan {
 		sendToLoggers(loggers, rec)
 	}
+	if rec := t.drops.report(time.Now()); rec != nil {
+		sendToLoggers(loggers, rec)
+	}
+	if flushTicker != nil {
+		flushTicker.Stop()
+	}
+	for _, cLog := range loggers {
+		flushLogger(cLog, time.Now(), true)
+	}
 	closeAllWriters(loggers)
 }
 
+// Set up the dispatch goroutine's state for a new or modified logger
+func (cLog *ConfigLogger) compile(timberStack *Level) {
+	cLog.timberStack = timberStack
+	cLog.granulars = compileGranulars(cLog.Granulars)
+	cLog.limiter = newRateLimiter(cLog.RateLimit, time.Now())
+	cLog.dedup = newDeduper(cLog.DedupWindow)
+	cLog.queue = newWriterQueue(*cLog)
+}
+
+// Format and write the record, or queue it for the logger's own goroutine
+func (cLog ConfigLogger) write(rec *LogRecord) {
+	if cLog.queue != nil {
+		cLog.queue.write(rec)
+		return
+	}
+	cLog.LogWriter.LogWrite(cLog.Formatter.Format(rec))
+}
+
+// Write everything queued for the logger and flush its writer
+func (cLog ConfigLogger) flush() {
+	if cLog.queue != nil {
+		cLog.queue.flush()
+	} else if cLog.LogWriter != nil {
+		flushWriter(cLog.LogWriter)
+	}
+}
+
+// Flush writers that buffer like FileWriter
+func flushWriter(writer LogWriter) {
+	if f, ok := writer.(flusher); ok {
+		f.Flush()
+	}
+}
+
+// Write the records a logger held back: the repeat count of the last message and
+// the rate limit summary, if they are due or force is set
+func flushLogger(cLog ConfigLogger, now time.Time, force bool) {
+	if cLog.LogWriter == nil {
+		return
+	}
+	if rec := cLog.dedup.flush(now, force); rec != nil {
+		cLog.write(rec)
+	}
+	if rec := cLog.limiter.summary(now, force); rec != nil {
+		cLog.write(rec)
+	}
+}
+
+// Level stored in Timber.minLevel when there are no loggers
+const disabledLevel = Level(1<<31 - 1)
+
+// Store the lowest level any logger or granular writes, and the lowest stack level of
+// the loggers.  Only called on the dispatch goroutine
+func (t *Timber) setMinLevel(loggers []ConfigLogger) {
+	min, minStack := disabledLevel, disabledLevel
+	for _, cLog := range loggers {
+		if cLog.LogWriter == nil {
+			continue
+		}
+		if cLog.Level < min {
+			min = cLog.Level
+		}
+		for _, lvl := range cLog.Granulars {
+			if lvl < min {
+				min = lvl
+			}
+		}
+		if cLog.StackLevel != NONE && cLog.StackLevel < minStack {
+			minStack = cLog.StackLevel
+		}
+	}
+	atomic.StoreInt32(t.minLevel, int32(min))
+	atomic.StoreInt32(t.loggerStackLevel, int32(minStack))
+}
+
+// Whether any logger would write a record at lvl so the caller can skip building
+// expensive arguments.  Filters, rate limits and the granulars that only raise the
+// level of some paths aren't taken into account so a record may still be discarded
+func (t *Timber) IsEnabled(lvl Level) bool {
+	min := Level(atomic.LoadInt32(t.minLevel))
+	return lvl >= min || min == NONE
+}
+
+// Whether there are any loggers at all
+func (t *Timber) Enabled() bool {
+	return Level(atomic.LoadInt32(t.minLevel)) != disabledLevel
+}
+
+// Send any records already queued without blocking for new ones
+func drainRecords(recordChan chan *LogRecord, loggers []ConfigLogger) {
+	for {
+		select {
+		case rec := <-recordChan:
+			sendToLoggers(loggers, rec)
+		default:
+			return
+		}
+	}
+}
+
 func sendToLogger(rec *LogRecord, granLevel Level, formatted string, cLog ConfigLogger) bool {
 	if rec.Level >= granLevel || granLevel == 0 {
+		if cLog.Filter != nil && !cLog.Filter.Allow(rec) {
+			return false
+		}
+		if !cLog.limiter.allow(rec) {
+			return false
+		}
+		if rec.Stack != nil && !cLog.wantsStack(rec) {
+			noStack := *rec
+			noStack.Stack = nil
+			rec = &noStack
+		}
+		repeat, repeated := cLog.dedup.check(rec)
+		if repeated != nil {
+			cLog.write(repeated)
+		}
+		if repeat {
+			return false
+		}
+		if cLog.queue != nil {
+			cLog.queue.write(rec)
+			return true
+		}
 		if formatted == "" {
 			formatted = cLog.Formatter.Format(rec)
 		}
@@ -342,15 +714,12 @@ func sendToLogger(rec *LogRecord, granLevel Level, formatted string, cLog Config
 func sendToLoggers(loggers []ConfigLogger, rec *LogRecord) {
 	formatted := ""
 	for _, cLog := range loggers {
-		// Find any function level definitions.
-		gLevel, ok := cLog.Granulars[rec.FuncPath]
-		if ok {
-			sendToLogger(rec, gLevel, formatted, cLog)
+		if cLog.LogWriter == nil {
+			// removed logger
 			continue
 		}
-		// Find any package level definitions.
-		gLevel, ok = cLog.Granulars[rec.PackagePath]
-		if ok {
+		// Find the most specific granular definition
+		if gLevel, ok := cLog.granulars.level(rec); ok {
 			sendToLogger(rec, gLevel, formatted, cLog)
 			continue
 		}
@@ -361,16 +730,26 @@ func sendToLoggers(loggers []ConfigLogger, rec *LogRecord) {
 
 func closeAllWriters(cls []ConfigLogger) {
 	for _, cLog := range cls {
-		cLog.LogWriter.Close()
+		// write everything queued first
+		cLog.queue.stop()
+		if cLog.LogWriter != nil {
+			cLog.LogWriter.Close()
+		}
 	}
 }
 
 // MultiLogger interface
+// Returns -1 without adding the logger once the Timber is closed
 func (t *Timber) AddLogger(logger ConfigLogger) int {
 	tcChan := make(chan int, 1) // buffered
 	tc := timberConfig{Action: actionAdd, Cfg: logger, Ret: tcChan}
-	t.writerConfigChan <- tc
-	return <-tcChan
+	select {
+	case <-t.blackHole:
+		// closed so nothing would be written
+		return -1
+	case t.writerConfigChan <- tc:
+		return <-tcChan
+	}
 }
 
 // MultiLogger interface
@@ -383,47 +762,167 @@ func (t *Timber) Close() {
 	})
 }
 
-// Not yet implemented
-func (t *Timber) SetLevel(index int, lvl Level) {
-	// TODO
+// Waits for the records logged so far to be written and flushes the writers that buffer.
+// Records held back by a rate limit or dedup window are still written when they're due
+func (t *Timber) Flush() {
+	tcChan := make(chan int, 1) // buffered
+	select {
+	case <-t.blackHole:
+		// closed so everything was written
+	case t.writerConfigChan <- timberConfig{Action: actionFlush, Ret: tcChan}:
+		<-tcChan
+	}
+}
+
+func (t *Timber) isClosed() bool {
+	select {
+	case <-t.blackHole:
+		return true
+	default:
+		return false
+	}
+}
+
+// MultiLogger interface
+// Changes the level threshold of the logger at index.  The change is made on the
+// dispatch goroutine after any records already queued have been written
+func (t *Timber) SetLevel(index int, lvl Level) error {
+	return t.modifyLogger(index, func(cLog *ConfigLogger) {
+		cLog.Level = lvl
+	})
+}
+
+// MultiLogger interface
+func (t *Timber) SetFormatter(index int, formatter LogFormatter) error {
+	return t.modifyLogger(index, func(cLog *ConfigLogger) {
+		cLog.Formatter = formatter
+	})
 }
 
-// Not yet implemented
-func (t *Timber) SetFormatter(index int, formatter LogFormatter) {
-	// TODO
+// MultiLogger interface
+// The index is not reused so indexes of the other loggers remain valid
+func (t *Timber) RemoveLogger(index int) error {
+	return t.modifyLogger(index, func(cLog *ConfigLogger) {
+		cLog.LogWriter.Close()
+		*cLog = ConfigLogger{}
+	})
+}
+
+// MultiLogger interface
+// The replaced LogWriter is always closed so use SetLevel or SetFormatter to keep it
+func (t *Timber) ReplaceLogger(index int, logger ConfigLogger) error {
+	if logger.LogWriter == nil {
+		return fmt.Errorf("TIMBER! Can't replace logger %d without a LogWriter", index)
+	}
+	return t.modifyLogger(index, func(cLog *ConfigLogger) {
+		cLog.LogWriter.Close()
+		*cLog = logger
+	})
+}
+
+func (t *Timber) modifyLogger(index int, modify func(*ConfigLogger)) error {
+	tcChan := make(chan int, 1) // buffered
+	tc := timberConfig{Action: actionModify, Index: index, Modify: modify, Ret: tcChan}
+	select {
+	case <-t.blackHole:
+		return fmt.Errorf("TIMBER! Can't change logger %d after Close", index)
+	case t.writerConfigChan <- tc:
+	}
+	if <-tcChan < 0 {
+		return fmt.Errorf("TIMBER! Unknown logger index: %d", index)
+	}
+	return nil
+}
+
+// Returns a child logger that adds the key/value pairs to every record it logs.
+// The child shares the dispatch goroutine and loggers of t so it is cheap to create
+// per request.  Configuring or closing the child configures or closes t.
+func (t *Timber) With(keysAndValues ...interface{}) *Timber {
+	child := *t
+	child.fields = make([]Field, 0, len(t.fields)+len(keysAndValues)/2)
+	child.fields = append(child.fields, t.fields...)
+	child.fields = append(child.fields, makeFields(keysAndValues)...)
+	return &child
+}
+
+// Returns a child logger like With that sets LogRecord.Name on every record.
+// Names of nested children are joined with a '.'.  The name may also be used
+// as a granular path
+func (t *Timber) Named(name string) *Timber {
+	child := *t
+	if t.name != "" {
+		name = t.name + "." + name
+	}
+	child.name = name
+	return &child
 }
 
 // Logger interface
-func (t *Timber) prepareAndSend(lvl Level, msg string, depth int) {
+// template is the message before any arguments were formatted into it
+func (t *Timber) prepareAndSend(lvl Level, template, msg string, depth int) {
+	t.prepareAndSendFields(lvl, template, msg, nil, nil, nil, depth+1)
+}
+
+// err is the error that was logged, if any.  stack is written by every logger but those
+// with StackOff, nil to capture one for the stack level
+func (t *Timber) prepareAndSendFields(lvl Level, template, msg string, fields []Field, err error, stack []StackFrame, depth int) {
+	if !t.IsEnabled(lvl) {
+		return
+	}
 	select {
 	case <-t.blackHole:
 		// the blackHole always blocks until we close
 		// then it always succeeds so we avoid writing
 		// to the closed channel
 	default:
-		t.recordChan <- t.prepare(lvl, msg, depth+1)
+		if len(t.fields) > 0 {
+			// copy so the bound fields are never shared with a caller's append
+			bound := make([]Field, 0, len(t.fields)+len(fields))
+			fields = append(append(bound, t.fields...), fields...)
+		}
+		t.send(t.prepare(lvl, template, msg, fields, err, stack, depth+1))
 	}
 }
 
-func (t *Timber) prepare(lvl Level, msg string, depth int) *LogRecord {
+func (t *Timber) prepare(lvl Level, template, msg string, fields []Field, err error, stack []StackFrame, depth int) *LogRecord {
 	now := time.Now()
-	pc, file, line, _ := runtime.Caller(depth)
+	// CallersFrames rather than FuncForPC so inlined callers get their own name.
+	// Callers counts itself as 0 and prepare as 1 which makes depth the caller of Info etc
+	var pcs [2]uintptr
+	var frame runtime.Frame
+	if n := runtime.Callers(depth, pcs[:]); n > 0 {
+		frames := runtime.CallersFrames(pcs[:n])
+		frame, _ = frames.Next()
+	}
+	file, line := frame.File, frame.Line
+	keepStack := stack != nil
+	if !keepStack && t.wantsStack(lvl) {
+		stack = callerStack(depth)
+	}
 	funcPath := "_"
 	packagePath := "_"
-	me := runtime.FuncForPC(pc)
-	if me != nil {
-		funcPath = me.Name()
-		packagePath = splitPackage(funcPath)
+	receiverType, funcName := "", "_"
+	if frame.Function != "" {
+		funcPath = frame.Function
+		packagePath, receiverType, funcName = splitFuncPath(funcPath)
 	}
 
 	return &LogRecord{
-		Level:       lvl,
-		Timestamp:   now,
-		SourceFile:  file,
-		SourceLine:  line,
-		Message:     msg,
-		FuncPath:    funcPath,
-		PackagePath: packagePath,
+		Level:        lvl,
+		Timestamp:    now,
+		SourceFile:   file,
+		SourceLine:   line,
+		Message:      msg,
+		Template:     template,
+		FuncPath:     funcPath,
+		PackagePath:  packagePath,
+		ReceiverType: receiverType,
+		FuncName:     funcName,
+		Fields:       fields,
+		Name:         t.name,
+		Error:        err,
+		Stack:        stack,
+		keepStack:    keepStack,
 	}
 }
 
@@ -431,90 +930,132 @@ func (t *Timber) prepare(lvl Level, msg string, depth int) *LogRecord {
 // log.SetOutput().  It is not a general Writer interface and assumes one
 // message per call to Write. All messages are send at level INFO
 func (t *Timber) Write(p []byte) (n int, err error) {
-	t.prepareAndSend(INFO, string(bytes.TrimSpace(p)), 4)
+	t.prepareAndSend(INFO, "", string(bytes.TrimSpace(p)), 5)
 	return len(p), nil
 }
 
+// The template, message and logged error for the level methods.  arg0 is usually
+// a format string for args, which may wrap an error with %w like fmt.Errorf.  Like log4go
+// it may be a func() string that builds the message so it's only called for enabled levels
+// or by the Error method of the error Warn, Error and Critical return.  Anything else is
+// formatted with args by fmt.Sprint, so an error is the message as well as the logged error.
+// The logged error is arg0 if it's an error, otherwise the first error in args
+func formatArgs(arg0 interface{}, args []interface{}) (string, string, error) {
+	switch first := arg0.(type) {
+	case string:
+		return first, formatMessage(first, args), firstError(args)
+	case func() string:
+		return "", first(), nil
+	case error:
+		return "", fmt.Sprint(append([]interface{}{first}, args...)...), first
+	}
+	return "", fmt.Sprint(append([]interface{}{arg0}, args...)...), firstError(args)
+}
+
+// The level methods and the package-level functions both call these so the caller is
+// the same number of frames away.  depth is the FileDepth of the Timber
+func (t *Timber) logArgs(lvl Level, arg0 interface{}, args []interface{}, depth int) {
+	if !t.IsEnabled(lvl) {
+		return
+	}
+	template, msg, err := formatArgs(arg0, args)
+	t.prepareAndSendFields(lvl, template, msg, nil, err, nil, depth+1)
+}
+
+// Returns the error for Warn, Error and Critical
+func (t *Timber) logArgsError(lvl Level, arg0 interface{}, args []interface{}, depth int) error {
+	if !t.IsEnabled(lvl) {
+		return &unloggedError{arg0, args}
+	}
+	template, msg, err := formatArgs(arg0, args)
+	t.prepareAndSendFields(lvl, template, msg, nil, err, nil, depth+1)
+	return &loggedError{msg, err}
+}
+
+// Print and Println pass fmt.Sprint or fmt.Sprintln, Printf passes its format and no sprint
+func (t *Timber) logPrint(format string, sprint func(...interface{}) string, v []interface{}, depth int) {
+	if !t.IsEnabled(DEBUG) {
+		return
+	}
+	var msg string
+	if sprint != nil {
+		msg = sprint(v...)
+	} else {
+		msg = fmt.Sprintf(format, v...)
+	}
+	t.prepareAndSend(DEBUG, format, msg, depth+1)
+}
+
+func (t *Timber) logAndPanic(template, msg string, depth int) {
+	t.prepareAndSend(CRITICAL, template, msg, depth+1)
+	panic(msg)
+}
+
+func (t *Timber) logAndExit(template, msg string, depth int) {
+	t.prepareAndSend(CRITICAL, template, msg, depth+1)
+	t.Close()
+	os.Exit(1)
+}
+
 func (t *Timber) Finest(arg0 interface{}, args ...interface{}) {
-	t.prepareAndSend(FINEST, fmt.Sprintf(arg0.(string), args...), t.FileDepth)
+	t.logArgs(FINEST, arg0, args, t.FileDepth)
 }
 func (t *Timber) Fine(arg0 interface{}, args ...interface{}) {
-	t.prepareAndSend(FINE, fmt.Sprintf(arg0.(string), args...), t.FileDepth)
+	t.logArgs(FINE, arg0, args, t.FileDepth)
 }
 func (t *Timber) Debug(arg0 interface{}, args ...interface{}) {
-	t.prepareAndSend(DEBUG, fmt.Sprintf(arg0.(string), args...), t.FileDepth)
+	t.logArgs(DEBUG, arg0, args, t.FileDepth)
 }
 func (t *Timber) Trace(arg0 interface{}, args ...interface{}) {
-	t.prepareAndSend(TRACE, fmt.Sprintf(arg0.(string), args...), t.FileDepth)
+	t.logArgs(TRACE, arg0, args, t.FileDepth)
 }
 func (t *Timber) Info(arg0 interface{}, args ...interface{}) {
-	t.prepareAndSend(INFO, fmt.Sprintf(arg0.(string), args...), t.FileDepth)
+	t.logArgs(INFO, arg0, args, t.FileDepth)
 }
 func (t *Timber) Warn(arg0 interface{}, args ...interface{}) error {
-	msg := fmt.Sprintf(arg0.(string), args...)
-	t.prepareAndSend(WARNING, msg, t.FileDepth)
-	return errors.New(msg)
+	return t.logArgsError(WARNING, arg0, args, t.FileDepth)
 }
 func (t *Timber) Error(arg0 interface{}, args ...interface{}) error {
-	msg := fmt.Sprintf(arg0.(string), args...)
-	t.prepareAndSend(ERROR, msg, t.FileDepth)
-	return errors.New(msg)
+	return t.logArgsError(ERROR, arg0, args, t.FileDepth)
 }
 func (t *Timber) Critical(arg0 interface{}, args ...interface{}) error {
-	msg := fmt.Sprintf(arg0.(string), args...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	return errors.New(msg)
+	return t.logArgsError(CRITICAL, arg0, args, t.FileDepth)
 }
 func (t *Timber) Log(lvl Level, arg0 interface{}, args ...interface{}) {
-	t.prepareAndSend(lvl, fmt.Sprintf(arg0.(string), args...), t.FileDepth)
+	t.logArgs(lvl, arg0, args, t.FileDepth)
 }
 
 // Print won't work well with a pattern_logger because it explicitly adds
 // its own \n; so you'd have to write your own formatter to remove it
 func (t *Timber) Print(v ...interface{}) {
-	t.prepareAndSend(DEBUG, fmt.Sprint(v...), t.FileDepth)
+	t.logPrint("", fmt.Sprint, v, t.FileDepth)
 }
 func (t *Timber) Printf(format string, v ...interface{}) {
-	t.prepareAndSend(DEBUG, fmt.Sprintf(format, v...), t.FileDepth)
+	t.logPrint(format, nil, v, t.FileDepth)
 }
 
 // Println won't work well either with a pattern_logger because it explicitly adds
 // its own \n; so you'd have to write your own formatter to not have 2 \n's
 func (t *Timber) Println(v ...interface{}) {
-	t.prepareAndSend(DEBUG, fmt.Sprintln(v...), t.FileDepth)
+	t.logPrint("", fmt.Sprintln, v, t.FileDepth)
 }
 func (t *Timber) Panic(v ...interface{}) {
-	msg := fmt.Sprint(v...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	panic(msg)
+	t.logAndPanic("", fmt.Sprint(v...), t.FileDepth)
 }
 func (t *Timber) Panicf(format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	panic(msg)
+	t.logAndPanic(format, fmt.Sprintf(format, v...), t.FileDepth)
 }
 func (t *Timber) Panicln(v ...interface{}) {
-	msg := fmt.Sprintln(v...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	panic(msg)
+	t.logAndPanic("", fmt.Sprintln(v...), t.FileDepth)
 }
 func (t *Timber) Fatal(v ...interface{}) {
-	msg := fmt.Sprint(v...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	t.Close()
-	os.Exit(1)
+	t.logAndExit("", fmt.Sprint(v...), t.FileDepth)
 }
 func (t *Timber) Fatalf(format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	t.Close()
-	os.Exit(1)
+	t.logAndExit(format, fmt.Sprintf(format, v...), t.FileDepth)
 }
 func (t *Timber) Fatalln(v ...interface{}) {
-	msg := fmt.Sprintln(v...)
-	t.prepareAndSend(CRITICAL, msg, t.FileDepth)
-	t.Close()
-	os.Exit(1)
+	t.logAndExit("", fmt.Sprintln(v...), t.FileDepth)
 }
 
 //
@@ -526,29 +1067,68 @@ func (t *Timber) Fatalln(v ...interface{}) {
 // Default Timber Instance (used for all the package level function calls)
 var Global = NewTimber()
 
-// Simple wrappers for Logger interface
-func Finest(arg0 interface{}, args ...interface{})         { Global.Finest(arg0, args...) }
-func Fine(arg0 interface{}, args ...interface{})           { Global.Fine(arg0, args...) }
-func Debug(arg0 interface{}, args ...interface{})          { Global.Debug(arg0, args...) }
-func Trace(arg0 interface{}, args ...interface{})          { Global.Trace(arg0, args...) }
-func Info(arg0 interface{}, args ...interface{})           { Global.Info(arg0, args...) }
-func Warn(arg0 interface{}, args ...interface{}) error     { return Global.Warn(arg0, args...) }
-func Error(arg0 interface{}, args ...interface{}) error    { return Global.Error(arg0, args...) }
-func Critical(arg0 interface{}, args ...interface{}) error { return Global.Critical(arg0, args...) }
-func Log(lvl Level, arg0 interface{}, args ...interface{}) { Global.Log(lvl, arg0, args...) }
-func Print(v ...interface{})                               { Global.Print(v...) }
-func Printf(format string, v ...interface{})               { Global.Printf(format, v...) }
-func Println(v ...interface{})                             { Global.Println(v...) }
-func Panic(v ...interface{})                               { Global.Panic(v...) }
-func Panicf(format string, v ...interface{})               { Global.Panicf(format, v...) }
-func Panicln(v ...interface{})                             { Global.Panicln(v...) }
-func Fatal(v ...interface{})                               { Global.Fatal(v...) }
-func Fatalf(format string, v ...interface{})               { Global.Fatalf(format, v...) }
-func Fatalln(v ...interface{})                             { Global.Fatalln(v...) }
-
-func AddLogger(logger ConfigLogger) int { return Global.AddLogger(logger) }
-func Close()                            { Global.Close() }
-
-func LoadConfiguration(filename string)     { Global.LoadConfig(filename) }
-func LoadXMLConfiguration(filename string)  { Global.LoadXMLConfig(filename) }
-func LoadJSONConfiguration(filename string) { Global.LoadJSONConfig(filename) }
+// Simple wrappers for Logger interface.  They skip the Timber methods so the
+// caller is found at the same depth
+func Finest(arg0 interface{}, args ...interface{}) {
+	Global.logArgs(FINEST, arg0, args, Global.FileDepth)
+}
+func Fine(arg0 interface{}, args ...interface{}) {
+	Global.logArgs(FINE, arg0, args, Global.FileDepth)
+}
+func Debug(arg0 interface{}, args ...interface{}) {
+	Global.logArgs(DEBUG, arg0, args, Global.FileDepth)
+}
+func Trace(arg0 interface{}, args ...interface{}) {
+	Global.logArgs(TRACE, arg0, args, Global.FileDepth)
+}
+func Info(arg0 interface{}, args ...interface{}) {
+	Global.logArgs(INFO, arg0, args, Global.FileDepth)
+}
+func Warn(arg0 interface{}, args ...interface{}) error {
+	return Global.logArgsError(WARNING, arg0, args, Global.FileDepth)
+}
+func Error(arg0 interface{}, args ...interface{}) error {
+	return Global.logArgsError(ERROR, arg0, args, Global.FileDepth)
+}
+func Critical(arg0 interface{}, args ...interface{}) error {
+	return Global.logArgsError(CRITICAL, arg0, args, Global.FileDepth)
+}
+func Log(lvl Level, arg0 interface{}, args ...interface{}) {
+	Global.logArgs(lvl, arg0, args, Global.FileDepth)
+}
+func Print(v ...interface{})                 { Global.logPrint("", fmt.Sprint, v, Global.FileDepth) }
+func Printf(format string, v ...interface{}) { Global.logPrint(format, nil, v, Global.FileDepth) }
+func Println(v ...interface{})               { Global.logPrint("", fmt.Sprintln, v, Global.FileDepth) }
+func Panic(v ...interface{})                 { Global.logAndPanic("", fmt.Sprint(v...), Global.FileDepth) }
+func Panicf(format string, v ...interface{}) {
+	Global.logAndPanic(format, fmt.Sprintf(format, v...), Global.FileDepth)
+}
+func Panicln(v ...interface{}) { Global.logAndPanic("", fmt.Sprintln(v...), Global.FileDepth) }
+func Fatal(v ...interface{})   { Global.logAndExit("", fmt.Sprint(v...), Global.FileDepth) }
+func Fatalf(format string, v ...interface{}) {
+	Global.logAndExit(format, fmt.Sprintf(format, v...), Global.FileDepth)
+}
+func Fatalln(v ...interface{}) { Global.logAndExit("", fmt.Sprintln(v...), Global.FileDepth) }
+
+func IsEnabled(lvl Level) bool { return Global.IsEnabled(lvl) }
+func Enabled() bool            { return Global.Enabled() }
+func SetStackLevel(lvl Level)  { Global.SetStackLevel(lvl) }
+
+func AddLogger(logger ConfigLogger) int   { return Global.AddLogger(logger) }
+func SetLevel(index int, lvl Level) error { return Global.SetLevel(index, lvl) }
+func SetFormatter(index int, formatter LogFormatter) error {
+	return Global.SetFormatter(index, formatter)
+}
+func RemoveLogger(index int) error { return Global.RemoveLogger(index) }
+func ReplaceLogger(index int, logger ConfigLogger) error {
+	return Global.ReplaceLogger(index, logger)
+}
+func Close() { Global.Close() }
+func Flush() { Global.Flush() }
+
+func With(keysAndValues ...interface{}) *Timber { return Global.With(keysAndValues...) }
+func Named(name string) *Timber                 { return Global.Named(name) }
+
+func LoadConfiguration(filename string) error     { return Global.LoadConfig(filename) }
+func LoadXMLConfiguration(filename string) error  { return Global.LoadXMLConfig(filename) }
+func LoadJSONConfiguration(filename string) error { return Global.LoadJSONConfig(filename) }
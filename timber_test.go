@@ -64,3 +64,227 @@ func TestDoubleClose(t *testing.T) {
 	log.Close() // call Close twice	
 	log.Warn("Don't panic")
 }
+
+func TestChangeAfterClose(t *testing.T) {
+	log := NewTimber()
+	index := log.AddLogger(ConfigLogger{LogWriter: new(memWriter), Level: DEBUG, Formatter: NewPatFormatter("%M")})
+	log.Close()
+	// none of these may block on the stopped dispatch goroutine
+	if added := log.AddLogger(ConfigLogger{LogWriter: new(memWriter), Level: DEBUG}); added != -1 {
+		t.Errorf("got index %d after Close, expected -1", added)
+	}
+	for name, err := range map[string]error{
+		"SetLevel":      log.SetLevel(index, INFO),
+		"SetFormatter":  log.SetFormatter(index, NewPatFormatter("%L %M")),
+		"RemoveLogger":  log.RemoveLogger(index),
+		"ReplaceLogger": log.ReplaceLogger(index, ConfigLogger{LogWriter: new(memWriter)}),
+	} {
+		if err == nil {
+			t.Errorf("%s: no error after Close", name)
+		}
+	}
+}
+
+// Collects messages for inspection. Only read after the Timber is closed
+type memWriter struct {
+	msgs []string
+}
+
+func (w *memWriter) LogWrite(msg string) {
+	w.msgs = append(w.msgs, msg)
+}
+
+func (w *memWriter) Close() {}
+
+func TestSetLevelAndFormatter(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	idx := log.AddLogger(ConfigLogger{LogWriter: writer,
+		Level:     INFO,
+		Formatter: NewPatFormatter("%L %M")})
+	log.Debug("dropped")
+	if err := log.SetLevel(idx, DEBUG); err != nil {
+		t.Fatalf("SetLevel: %v", err)
+	}
+	log.Debug("kept")
+	if err := log.SetFormatter(idx, NewPatFormatter("[%L] %M")); err != nil {
+		t.Fatalf("SetFormatter: %v", err)
+	}
+	log.Info("reformatted")
+	if err := log.SetLevel(idx+1, DEBUG); err == nil {
+		t.Errorf("expected error for unknown index")
+	}
+	if err := log.SetFormatter(-1, NewPatFormatter("%M")); err == nil {
+		t.Errorf("expected error for unknown index")
+	}
+	log.Close()
+
+	expected := []string{"DEBG kept\n", "[INFO] reformatted\n"}
+	if len(writer.msgs) != len(expected) {
+		t.Fatalf("got %q, expected %q", writer.msgs, expected)
+	}
+	for i, msg := range expected {
+		verify(t, "SetLevel", writer.msgs[i], msg)
+	}
+}
+
+type closeCountWriter struct {
+	memWriter
+	closed int
+}
+
+func (w *closeCountWriter) Close() { w.closed++ }
+
+func TestRemoveAndReplaceLogger(t *testing.T) {
+	log := NewTimber()
+	first := new(closeCountWriter)
+	second := new(closeCountWriter)
+	replacement := new(closeCountWriter)
+	formatter := NewPatFormatter("%M")
+	idx1 := log.AddLogger(ConfigLogger{LogWriter: first, Level: DEBUG, Formatter: formatter})
+	idx2 := log.AddLogger(ConfigLogger{LogWriter: second, Level: DEBUG, Formatter: formatter})
+	log.Info("both")
+	if err := log.RemoveLogger(idx1); err != nil {
+		t.Fatalf("RemoveLogger: %v", err)
+	}
+	if err := log.RemoveLogger(idx1); err == nil {
+		t.Errorf("expected error removing a removed logger")
+	}
+	if err := log.SetLevel(idx1, INFO); err == nil {
+		t.Errorf("expected error modifying a removed logger")
+	}
+	log.Info("second")
+	if err := log.ReplaceLogger(idx2, ConfigLogger{LogWriter: replacement, Level: DEBUG, Formatter: formatter}); err != nil {
+		t.Fatalf("ReplaceLogger: %v", err)
+	}
+	log.Info("replacement")
+	log.Close()
+
+	if first.closed != 1 || second.closed != 1 || replacement.closed != 1 {
+		t.Errorf("close counts %d %d %d, expected 1 1 1", first.closed, second.closed, replacement.closed)
+	}
+	checkMsgs(t, first.msgs, []string{"both\n"})
+	checkMsgs(t, second.msgs, []string{"both\n", "second\n"})
+	checkMsgs(t, replacement.msgs, []string{"replacement\n"})
+}
+
+func checkMsgs(t *testing.T, msgs, expected []string) {
+	if len(msgs) != len(expected) {
+		t.Errorf("got %q, expected %q", msgs, expected)
+		return
+	}
+	for i, msg := range expected {
+		verify(t, "message", msgs[i], msg)
+	}
+}
+
+func TestFieldLogging(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer,
+		Level:     DEBUG,
+		Formatter: NewPatFormatter("%L %M %F")})
+	log.Infow("request done", "user", 7, "latency", "3ms")
+	log.Info("plain %d", 1)
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"INFO request done user=7 latency=3ms\n", "INFO plain 1 \n"})
+}
+
+func TestFileDepth(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: DEBUG, Formatter: NewPatFormatter("%f %M")})
+	saved := Global
+	Global = log
+	defer func() { Global = saved }()
+
+	wrapped := log.With()
+	wrapped.FileDepth = DefaultFileDepth + 1
+	wrapper := func(msg string) { wrapped.Info(msg) }
+	packageWrapper := func(msg string) {
+		Global.FileDepth++
+		defer func() { Global.FileDepth-- }()
+		Info(msg)
+	}
+
+	log.Info("direct")
+	Info("package")
+	wrapper("wrapper")
+	packageWrapper("package wrapper")
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{
+		"TestFileDepth direct\n",
+		"TestFileDepth package\n",
+		"TestFileDepth wrapper\n",
+		"TestFileDepth package wrapper\n",
+	})
+}
+
+func TestMessageClosure(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer, Level: ERROR, Formatter: NewPatFormatter("%L %M")})
+	built := 0
+	build := func() string {
+		built++
+		return "built"
+	}
+	log.Error(build)
+	log.Info(build)
+	err := log.Warn(build)
+	if built != 1 {
+		t.Errorf("built the message %d times, expected 1", built)
+	}
+	// the error from a disabled level builds the message when it's used
+	if err.Error() != "built" || built != 2 {
+		t.Errorf("got %q after %d builds, expected built after 2", err.Error(), built)
+	}
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{"EROR built\n"})
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+
+func TestNonStringArgs(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer,
+		Level:     DEBUG,
+		Formatter: NewPatFormatter("%L %M %F")})
+	log.Info(42)
+	log.Info(3.5, " and ", 7)
+	log.Error(timeoutError{})
+	log.Warn(nil)
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{
+		"INFO 42 \n",
+		"INFO 3.5 and 7 \n",
+		"EROR i/o timeout \n",
+		"WARN <nil> \n",
+	})
+}
+
+func TestWithAndNamed(t *testing.T) {
+	log := NewTimber()
+	writer := new(memWriter)
+	log.AddLogger(ConfigLogger{LogWriter: writer,
+		Level:     INFO,
+		Formatter: NewPatFormatter("%N|%M|%F"),
+		Granulars: map[string]Level{"billing.db": DEBUG}})
+	reqLog := log.With("request_id", "abc")
+	reqLog.Infow("start", "step", 1)
+	reqLog.With("user", 7).Info("nested")
+	billing := log.Named("billing")
+	billing.Debug("dropped by level")
+	billing.Named("db").With("table", "invoices").Debug("kept by granular")
+	log.Info("parent")
+	log.Close()
+	checkMsgs(t, writer.msgs, []string{
+		"|start|request_id=abc step=1\n",
+		"|nested|request_id=abc user=7\n",
+		"billing.db|kept by granular|table=invoices\n",
+		"|parent|\n",
+	})
+}